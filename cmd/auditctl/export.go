@@ -0,0 +1,138 @@
+package main
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/export"
+	"audit-sendiri/internal/query"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+)
+
+func runExport(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var opts export.Options
+	fs.StringVar(&opts.Format, "format", export.FormatCSV, "csv, ndjson, beancount or ledger (journals: transactions only)")
+	fs.BoolVar(&opts.BOM, "bom", true, "start CSV output with a UTF-8 byte order mark")
+	fs.BoolVar(&opts.DecimalComma, "decimal-comma", false, "write amounts with a decimal comma and separate CSV fields with semicolons")
+	filter := fs.String("filter", "", "listing filters as a query string, e.g. \"from=2024-01-01&to=2024-12-31&type=expense\"")
+	out := fs.String("o", "", "output file (default: standard output)")
+	as := fs.String("as", "", "username recorded in the audit log as the exporter (required)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: auditctl export [flags] transactions|audit-log")
+		fmt.Fprintln(os.Stderr, "\nThe export is recorded in the audit log, so the data directory is written to; it fails while the server is running.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	dataset := fs.Arg(0)
+	if dataset != "transactions" && dataset != "audit-log" {
+		return fmt.Errorf("unknown dataset %q; use transactions or audit-log", dataset)
+	}
+	if *as == "" {
+		return errors.New("-as is required")
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	opts.BOM = opts.BOM && opts.Format == export.FormatCSV
+
+	values, err := url.ParseQuery(*filter)
+	if err != nil {
+		return fmt.Errorf("invalid -filter: %w", err)
+	}
+	get := query.Values(values)
+	opts.Location, err = time.LoadLocation(get("tz", query.DefaultTimezone))
+	if err != nil {
+		return err
+	}
+
+	store, err := openWritable(dataDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	user, ok := store.UserByUsername(*as)
+	if !ok {
+		return fmt.Errorf("user %q not found", *as)
+	}
+
+	var count int
+	var write func(w io.Writer) error
+	audited := "transactions"
+	switch {
+	case dataset == "transactions" && opts.Journal():
+		var errs domain.ValidationErrors
+		r := query.ParseDateRangeOnly(get, &errs)
+		if len(errs) > 0 {
+			return errs
+		}
+		var j *export.Journal
+		store.View(func(s domain.Snapshot) error {
+			settings := s.Settings()
+			title := fmt.Sprintf("Buku Kas RT %s / RW %s", settings.RTName, settings.RWName)
+			j = export.NewJournal(title, s.Transactions(), s.Categories(), s.AuditLogs(), r.From, r.To, r.Location)
+			return nil
+		})
+		count = len(j.Entries)
+		write = func(w io.Writer) error { return j.Write(w, opts.Format) }
+	case dataset == "transactions":
+		stmt, _, _, err := query.Transactions(get, store.Categories(), false)
+		if err != nil {
+			return err
+		}
+		txs, _, err := store.QueryTransactions(stmt)
+		if err != nil {
+			return err
+		}
+		count = len(txs)
+		write = func(w io.Writer) error { return export.Transactions(w, txs, opts) }
+	case opts.Journal():
+		return errors.New("the audit log can only be exported as csv or ndjson")
+	default:
+		var errs domain.ValidationErrors
+		f := query.ParseAuditFilter(get, &errs)
+		if len(errs) > 0 {
+			return errs
+		}
+		logs := f.Filter(store.AuditLogs())
+		count = len(logs)
+		audited = "audit_log"
+		write = func(w io.Writer) error { return export.AuditLogs(w, logs, opts) }
+	}
+
+	a := domain.Actor{UserID: user.ID, Username: user.Username, UserAgent: "auditctl export"}
+	err = store.Update(func(t domain.Repositories) error {
+		entry := export.AuditEntry(audited, opts, *filter, count, time.Now())
+		entry.Stamp(a)
+		return t.InsertAuditLog(entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d records to %s\n", count, *out)
+	return nil
+}
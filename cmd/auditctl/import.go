@@ -0,0 +1,141 @@
+package main
+
+import (
+	"audit-sendiri/internal/db"
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/importer"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func runImport(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var opts importer.Options
+	fs.StringVar(&opts.Mapping.Date, "date", "", "date column (header or 1-based number)")
+	fs.StringVar(&opts.Mapping.Type, "type", "", "type column")
+	fs.StringVar(&opts.Mapping.Amount, "amount", "", "amount column")
+	fs.StringVar(&opts.Mapping.Income, "income", "", "income column")
+	fs.StringVar(&opts.Mapping.Expense, "expense", "", "expense column")
+	fs.StringVar(&opts.Mapping.Category, "category", "", "category column")
+	fs.StringVar(&opts.Mapping.Description, "description", "", "description column")
+	fs.StringVar(&opts.DateFormat, "date-format", "", "Go time layout for the date column")
+	fs.BoolVar(&opts.CreateCategories, "create-categories", false, "create categories that do not exist yet")
+	delimiter := fs.String("delimiter", "", "CSV delimiter (default: detected)")
+	tz := fs.String("tz", "Asia/Jakarta", "timezone of dates without one")
+	commit := fs.Bool("commit", false, "write the transactions; without it only a preview is printed")
+	as := fs.String("as", "", "username recorded as the importer (required with -commit)")
+	asJSON := fs.Bool("json", false, "print the preview as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: auditctl import [flags] FILE.csv|FILE.xlsx")
+		fmt.Fprintln(os.Stderr, "\nWith -commit the data directory is written to; it fails while the server is running.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	file := fs.Arg(0)
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return err
+	}
+	opts.Location = loc
+	var delim rune
+	switch *delimiter {
+	case "":
+	case "tab":
+		delim = '\t'
+	default:
+		if len(*delimiter) != 1 {
+			return fmt.Errorf("invalid delimiter %q", *delimiter)
+		}
+		delim = rune((*delimiter)[0])
+	}
+	if *commit && *as == "" {
+		return errors.New("-as is required with -commit")
+	}
+
+	format, err := importer.FormatOf(file)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	rows, err := importer.ReadRows(data, format, delim)
+	if err != nil {
+		return err
+	}
+
+	var store *db.SawitDB
+	if *commit {
+		store, err = openWritable(dataDir)
+	} else {
+		store, err = db.Open(dataDir, db.Options{ReadOnly: true})
+	}
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	opts.ClosedPeriods = store.ClosedPeriods()
+	job, err := importer.Parse(rows, filepath.Base(file), format, store.Categories(), opts)
+	if err != nil {
+		return err
+	}
+
+	if *commit && job.InvalidRows == 0 {
+		user, ok := store.UserByUsername(*as)
+		if !ok {
+			return fmt.Errorf("user %q not found", *as)
+		}
+		a := domain.Actor{UserID: user.ID, Username: user.Username, UserAgent: "auditctl import"}
+		err := store.Update(func(t domain.Repositories) error {
+			return job.Commit(t, a, time.Now())
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(job); err != nil {
+			return err
+		}
+	} else {
+		printImport(job)
+	}
+
+	switch {
+	case job.InvalidRows > 0:
+		return fmt.Errorf("%d invalid rows", job.InvalidRows)
+	case *commit:
+		fmt.Printf("OK: imported %d transactions as job %s\n", job.ValidRows, job.ID)
+	default:
+		fmt.Println("Preview only; run again with -commit -as USERNAME to import.")
+	}
+	return nil
+}
+
+func printImport(job *importer.Job) {
+	fmt.Printf("File:        %s (%s)\n", job.Filename, job.Format)
+	fmt.Printf("Rows:        %d valid, %d invalid\n", job.ValidRows, job.InvalidRows)
+	fmt.Printf("Income:      %s\n", job.TotalIncome)
+	fmt.Printf("Expense:     %s\n", job.TotalExpense)
+	for _, c := range job.NewCategories {
+		fmt.Printf("New category: %s (%s)\n", c.Name, c.Kind)
+	}
+	for _, e := range job.Errors {
+		fmt.Printf("line %d: %v\n", e.Line, e.Errors)
+	}
+}
@@ -0,0 +1,84 @@
+// Command auditctl inspects and maintains an AuditSendiri data directory.
+//
+// Usage:
+//
+//	auditctl [-data DIR] <command> [flags]
+//
+// Commands:
+//
+//	verify-audit   walk the audit log hash chain and report the first broken link
+//	import         preview or import transactions from a CSV or XLSX file
+//	export         export transactions or the audit log as CSV or NDJSON
+package main
+
+import (
+	"audit-sendiri/internal/db"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(dataDir string, args []string) error
+}
+
+var commands = []command{
+	{"verify-audit", "walk the audit log hash chain and report the first broken link", runVerifyAudit},
+	{"import", "preview or import transactions from a CSV or XLSX file", runImport},
+	{"export", "export transactions or the audit log as CSV or NDJSON", runExport},
+}
+
+func main() {
+	// The server reads DB_PATH from .env too; both must find the same
+	// data directory.
+	if godotenv.Load() != nil {
+		godotenv.Load("../.env")
+	}
+	dataDir := flag.String("data", db.DataDir(), "data directory containing data.sawit")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(*dataDir, flag.Args()[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "auditctl %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "auditctl: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: auditctl [-data DIR] <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nflags:")
+	flag.PrintDefaults()
+}
+
+// openWritable opens the data directory for a command that writes to it. It
+// fails at once while the server, or another auditctl, holds the directory.
+func openWritable(dataDir string) (*db.SawitDB, error) {
+	store, err := db.Open(dataDir, db.Options{SnapshotEvery: db.DefaultSnapshotEvery})
+	if errors.Is(err, db.ErrLocked) {
+		return nil, fmt.Errorf("%s is in use, most likely by the running server; stop it and try again", dataDir)
+	}
+	return store, err
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"audit-sendiri/internal/db"
+	"audit-sendiri/internal/domain"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runVerifyAudit(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("verify-audit", flag.ExitOnError)
+	fs.Parse(args)
+
+	store, err := db.Open(dataDir, db.Options{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report := domain.VerifyAuditChain(store.AuditLogs())
+	if report.Valid {
+		fmt.Printf("OK: %d audit entries verified\n", report.Checked)
+		fmt.Printf("chain head: %s\n", report.Head)
+		return nil
+	}
+
+	fmt.Printf("BROKEN: %d entries verified before the first broken link\n", report.Checked)
+	fmt.Printf("entry #%d (id %s): %s\n", *report.BrokenIndex+1, report.BrokenID, report.Reason)
+	return errors.New("audit chain verification failed")
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"audit-sendiri/internal/api"
 	"audit-sendiri/internal/db"
+	"audit-sendiri/internal/ledger"
 	"log"
 	"os"
+	"strconv"
+	_ "time/tzdata"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -21,12 +24,34 @@ func main() {
 		log.Println("You can set them manually or create a .env file in the project root.")
 	}
 
-	database, err := db.NewSawitDB("./data")
+	opts := db.Options{SnapshotEvery: db.DefaultSnapshotEvery}
+	if v := os.Getenv("SNAPSHOT_EVERY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("Invalid SNAPSHOT_EVERY: %v", err)
+		}
+		opts.SnapshotEvery = n
+	}
+	if os.Getenv("SAWIT_REPAIR") == "true" {
+		log.Println("WARNING: SAWIT_REPAIR is enabled; a corrupt log will be truncated at the first bad record.")
+		opts.Repair = true
+	}
+
+	dataDir := db.DataDir()
+	database, err := db.Open(dataDir, opts)
 	if err != nil {
 		log.Fatalf("Failed to initialize DB: %v", err)
 	}
-	database.Migrate()
-	app := fiber.New()
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate DB: %v", err)
+	}
+	app := fiber.New(fiber.Config{
+		// Bodies are streamed rather than buffered up front, so imports can
+		// exceed the limit while api.BodyLimit holds every other route to it.
+		BodyLimit:                    api.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
 	if allowedOrigins == "" {
 		allowedOrigins = "http://localhost:5173,http://localhost:3000"
@@ -41,7 +66,7 @@ func main() {
 		MaxAge:           86400,
 	}))
 
-	handler := api.NewHandler(database)
+	handler := api.NewHandler(database, ledger.NewKeyStore(dataDir))
 	handler.Register(app)
 
 	app.Static("/", "./frontend/dist")
@@ -0,0 +1,73 @@
+// Command verify-ledger checks a ledger file downloaded from an
+// AuditSendiri instance's /api/ledger/export endpoint. It needs no network
+// access and no data directory.
+package main
+
+import (
+	"audit-sendiri/internal/ledger"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	key := flag.String("key", "", "expected public key (base64), as published by the RT")
+	keyID := flag.String("key-id", "", "expected key fingerprint, as published by the RT")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: verify-ledger [-key BASE64 | -key-id FINGERPRINT] ledger.json\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *key, *keyID); err != nil {
+		fmt.Fprintf(os.Stderr, "INVALID: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, key, keyID string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var trusted ed25519.PublicKey
+	if key != "" {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return fmt.Errorf("-key is not a base64 Ed25519 public key")
+		}
+		trusted = raw
+	}
+
+	signed, l, err := ledger.Verify(data, trusted)
+	if err != nil {
+		return err
+	}
+	if keyID != "" && !strings.EqualFold(keyID, signed.KeyID) {
+		return fmt.Errorf("signed by key %s, expected %s", signed.KeyID, keyID)
+	}
+
+	fmt.Printf("OK: signature valid\n")
+	fmt.Printf("Issuer:        RT %s / RW %s, %s, %s\n", l.Issuer.RTName, l.Issuer.RWName, l.Issuer.Kelurahan, l.Issuer.Kecamatan)
+	fmt.Printf("Generated:     %s\n", l.GeneratedAt.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("Transactions:  %d\n", len(l.Transactions))
+	fmt.Printf("Income:        %s\n", l.TotalIncome)
+	fmt.Printf("Expense:       %s\n", l.TotalExpense)
+	fmt.Printf("Balance:       %s\n", l.Balance)
+	fmt.Printf("Audit head:    %s (%d entries)\n", l.AuditChainHead, l.AuditEntries)
+	fmt.Printf("Key ID:        %s\n", signed.KeyID)
+	if key == "" && keyID == "" {
+		fmt.Println("\nNo -key or -key-id given: this only proves the file was not altered after")
+		fmt.Println("signing. Compare the key ID above with the one your RT published.")
+	}
+	return nil
+}
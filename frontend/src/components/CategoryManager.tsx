@@ -0,0 +1,107 @@
+import { useEffect, useState } from "react";
+import { Button } from "./ui/Button";
+import { Card, CardHeader, CardTitle, CardContent } from "./ui/Card";
+import { Input } from "./ui/Input";
+import { Plus, Trash2 } from "lucide-react";
+import { useToast } from "./ui/use-toast";
+import { getCategories, createCategory, updateCategory, deleteCategory, type Category } from "../lib/api";
+
+const errorMessage = (error: unknown, fallback: string) => {
+    const err = error as { response?: { data?: { error?: string } } };
+    return err.response?.data?.error ?? fallback;
+};
+
+export default function CategoryManager({ isAdmin }: { isAdmin: boolean }) {
+    const { toast } = useToast();
+    const [categories, setCategories] = useState<Category[]>([]);
+    const [form, setForm] = useState({ kind: 'income' as Category['kind'], name: '', code: '' });
+
+    const fetchCategories = () => {
+        getCategories().then(setCategories).catch(console.error);
+    };
+
+    useEffect(() => {
+        fetchCategories();
+    }, []);
+
+    const handleCreate = async (e: React.FormEvent) => {
+        e.preventDefault();
+        try {
+            await createCategory(form);
+            setForm({ ...form, name: '', code: '' });
+            fetchCategories();
+        } catch (error) {
+            toast({ variant: "destructive", title: "Gagal", description: errorMessage(error, "Gagal menambah kategori") });
+        }
+    };
+
+    const handleToggle = async (c: Category) => {
+        try {
+            await updateCategory(c.id, { active: !c.active });
+            fetchCategories();
+        } catch (error) {
+            toast({ variant: "destructive", title: "Gagal", description: errorMessage(error, "Gagal mengubah kategori") });
+        }
+    };
+
+    const handleDelete = async (c: Category) => {
+        if (!window.confirm(`Hapus kategori ${c.name}?`)) return;
+        try {
+            await deleteCategory(c.id);
+            fetchCategories();
+        } catch (error) {
+            toast({ variant: "destructive", title: "Gagal", description: errorMessage(error, "Gagal menghapus kategori") });
+        }
+    };
+
+    const section = (kind: Category['kind'], title: string) => (
+        <div className="space-y-2">
+            <h4 className="font-semibold text-sm">{title}</h4>
+            {categories.filter(c => c.kind === kind).map(c => (
+                <div key={c.id} className="flex items-center justify-between gap-2 text-sm border-b border-white/5 pb-2">
+                    <div className={c.active ? "" : "text-muted-foreground line-through"}>
+                        {c.parent_id && <span className="text-muted-foreground">↳ </span>}
+                        {c.name} <span className="text-xs text-muted-foreground">({c.code})</span>
+                    </div>
+                    {isAdmin && (
+                        <div className="flex gap-1">
+                            <Button type="button" variant="ghost" size="sm" onClick={() => handleToggle(c)}>
+                                {c.active ? "Nonaktifkan" : "Aktifkan"}
+                            </Button>
+                            <Button type="button" variant="ghost" size="sm" onClick={() => handleDelete(c)} className="text-red-500 hover:text-red-600">
+                                <Trash2 className="h-4 w-4" />
+                            </Button>
+                        </div>
+                    )}
+                </div>
+            ))}
+        </div>
+    );
+
+    return (
+        <Card className="glass-card border-none shadow-lg">
+            <CardHeader>
+                <CardTitle>Kategori Transaksi</CardTitle>
+            </CardHeader>
+            <CardContent className="space-y-6">
+                <div className="grid gap-6 md:grid-cols-2">
+                    {section('income', 'Pemasukan')}
+                    {section('expense', 'Pengeluaran')}
+                </div>
+                {isAdmin && (
+                    <form onSubmit={handleCreate} className="flex flex-col md:flex-row gap-2">
+                        <select value={form.kind} onChange={e => setForm({ ...form, kind: e.target.value as Category['kind'] })} className="flex h-10 rounded-md border border-input bg-background/50 px-3 py-2 text-sm">
+                            <option value="income">Pemasukan</option>
+                            <option value="expense">Pengeluaran</option>
+                        </select>
+                        <Input placeholder="Nama kategori" required value={form.name} onChange={e => setForm({ ...form, name: e.target.value })} />
+                        <Input placeholder="Kode (opsional)" value={form.code} onChange={e => setForm({ ...form, code: e.target.value })} className="md:w-40" />
+                        <Button type="submit" variant="neon">
+                            <Plus className="mr-2 h-4 w-4" /> Tambah
+                        </Button>
+                    </form>
+                )}
+            </CardContent>
+        </Card>
+    );
+}
@@ -0,0 +1,114 @@
+import { useEffect, useState } from "react";
+import { Button } from "./ui/Button";
+import { Card, CardHeader, CardTitle, CardContent } from "./ui/Card";
+import { Input } from "./ui/Input";
+import { Lock, Unlock } from "lucide-react";
+import { useToast } from "./ui/use-toast";
+import { getPeriods, closePeriod, reopenPeriod, type ClosedPeriod } from "../lib/api";
+
+const errorMessage = (error: unknown, fallback: string) => {
+    const err = error as { response?: { data?: { error?: string } } };
+    return err.response?.data?.error ?? fallback;
+};
+
+const formatCurrency = (amount: number) =>
+    new Intl.NumberFormat("id-ID", { style: "currency", currency: "IDR" }).format(amount);
+
+// lastMonth is the most recent month that can be closed, as YYYY-MM.
+const lastMonth = () => {
+    const d = new Date();
+    d.setDate(1);
+    d.setMonth(d.getMonth() - 1);
+    return `${d.getFullYear()}-${String(d.getMonth() + 1).padStart(2, "0")}`;
+};
+
+export default function PeriodManager({ isAdmin }: { isAdmin: boolean }) {
+    const { toast } = useToast();
+    const [periods, setPeriods] = useState<ClosedPeriod[]>([]);
+    const [form, setForm] = useState({ month: lastMonth(), note: '' });
+
+    const fetchPeriods = () => {
+        getPeriods().then(setPeriods).catch(console.error);
+    };
+
+    useEffect(() => {
+        fetchPeriods();
+    }, []);
+
+    const handleClose = async (e: React.FormEvent) => {
+        e.preventDefault();
+        const [year, month] = form.month.split("-").map(Number);
+        if (!window.confirm(`Tutup buku ${form.month}? Transaksi pada bulan ini tidak bisa diubah lagi.`)) return;
+        try {
+            await closePeriod(year, month, form.note);
+            setForm({ ...form, note: '' });
+            fetchPeriods();
+        } catch (error) {
+            toast({ variant: "destructive", title: "Gagal", description: errorMessage(error, "Gagal menutup buku") });
+        }
+    };
+
+    const handleReopen = async (p: ClosedPeriod) => {
+        const reason = window.prompt(`Alasan membuka kembali ${p.label}:`);
+        if (!reason?.trim()) return;
+        try {
+            await reopenPeriod(p.id, reason);
+            fetchPeriods();
+        } catch (error) {
+            toast({ variant: "destructive", title: "Gagal", description: errorMessage(error, "Gagal membuka kembali periode") });
+        }
+    };
+
+    return (
+        <Card className="glass-card border-none shadow-lg">
+            <CardHeader>
+                <CardTitle>Tutup Buku</CardTitle>
+            </CardHeader>
+            <CardContent className="space-y-6">
+                <p className="text-sm text-muted-foreground">
+                    Bulan yang sudah ditutup terkunci: transaksinya tidak bisa ditambah, diubah, dihapus, atau dipulihkan.
+                    Koreksi dicatat sebagai transaksi penyesuaian di periode yang masih terbuka.
+                </p>
+                <div className="space-y-2">
+                    {periods.length === 0 && (
+                        <p className="text-sm text-muted-foreground">Belum ada periode yang ditutup.</p>
+                    )}
+                    {[...periods].reverse().map(p => (
+                        <div key={p.id} className="flex items-center justify-between gap-2 text-sm border-b border-white/5 pb-2">
+                            <div className={p.reopened_at ? "text-muted-foreground" : ""}>
+                                <div className="flex items-center gap-2 font-medium">
+                                    {p.reopened_at ? <Unlock className="h-4 w-4" /> : <Lock className="h-4 w-4 text-primary" />}
+                                    {p.label}
+                                    <span className="text-xs text-muted-foreground">saldo akhir {formatCurrency(p.closing_balance)}</span>
+                                </div>
+                                <div className="text-xs text-muted-foreground">
+                                    Ditutup {new Date(p.closed_at).toLocaleDateString("id-ID")} oleh {p.closed_by_username ?? "-"}
+                                    {p.note && ` — ${p.note}`}
+                                </div>
+                                {p.reopened_at && (
+                                    <div className="text-xs text-muted-foreground">
+                                        Dibuka kembali {new Date(p.reopened_at).toLocaleDateString("id-ID")} oleh {p.reopened_by_username ?? "-"}: {p.reopen_reason}
+                                    </div>
+                                )}
+                            </div>
+                            {isAdmin && !p.reopened_at && (
+                                <Button type="button" variant="ghost" size="sm" onClick={() => handleReopen(p)}>
+                                    Buka Kembali
+                                </Button>
+                            )}
+                        </div>
+                    ))}
+                </div>
+                {isAdmin && (
+                    <form onSubmit={handleClose} className="flex flex-col md:flex-row gap-2">
+                        <Input type="month" required max={lastMonth()} value={form.month} onChange={e => setForm({ ...form, month: e.target.value })} className="md:w-48" />
+                        <Input placeholder="Catatan (opsional), mis. dibacakan di rapat warga" value={form.note} onChange={e => setForm({ ...form, note: e.target.value })} />
+                        <Button type="submit" variant="neon">
+                            <Lock className="mr-2 h-4 w-4" /> Tutup Buku
+                        </Button>
+                    </form>
+                )}
+            </CardContent>
+        </Card>
+    );
+}
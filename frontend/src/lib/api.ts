@@ -51,9 +51,34 @@ export interface Transaction {
     type: 'income' | 'expense';
     amount: number;
     category: string;
+    category_id?: string;
     description: string;
+    // transaction_date is when the money changed hands; older records
+    // without one use created_at.
+    transaction_date?: string;
     created_at: string;
     created_by: string;
+    created_by_username?: string;
+    updated_at?: string;
+    updated_by?: string;
+    updated_by_username?: string;
+    // adjustment_of is the transaction this adjusting entry corrects.
+    adjustment_of?: string;
+    // In immutable-ledger mode, reversal_of marks the entry cancelling a
+    // transaction and correction_of the entry replacing it.
+    reversal_of?: string;
+    correction_of?: string;
+}
+
+export interface Category {
+    id: string;
+    kind: 'income' | 'expense';
+    code: string;
+    name: string;
+    parent_id?: string;
+    active: boolean;
+    created_at: string;
+    updated_at: string;
 }
 
 export interface SetupCheckResponse {
@@ -70,8 +95,118 @@ export const checkSetup = async (): Promise<boolean> => {
     }
 };
 
-export const getTransactions = async (): Promise<Transaction[]> => {
-    const response = await api.get<Transaction[]>('/transactions');
+export interface Page<T> {
+    data: T[];
+    total: number;
+    page: number;
+    limit: number;
+    pages: number;
+}
+
+export interface TransactionQuery {
+    from?: string;
+    to?: string;
+    tz?: string;
+    type?: 'income' | 'expense';
+    category?: string;
+    min_amount?: string;
+    max_amount?: string;
+    q?: string;
+    sort?: 'transaction_date' | 'created_at' | 'amount' | 'type' | 'category' | 'description';
+    order?: 'asc' | 'desc';
+    page?: number;
+    limit?: number;
+}
+
+export const getTransactions = async (query: TransactionQuery = {}): Promise<Page<Transaction>> => {
+    const response = await api.get<Page<Transaction>>('/transactions', { params: query });
+    return response.data;
+};
+
+export interface ExportOptions {
+    format?: 'csv' | 'ndjson' | 'beancount' | 'ledger';
+    decimal?: 'point' | 'comma';
+}
+
+// downloadExport saves an audited export of the transactions or audit log
+// matching query. It goes through axios so the request carries the token.
+export const downloadExport = async (
+    dataset: 'transactions' | 'audit-log',
+    query: Omit<TransactionQuery, 'page' | 'limit'> = {},
+    options: ExportOptions = {},
+): Promise<void> => {
+    const response = await api.get<Blob>(`/${dataset}/export`, {
+        params: { ...query, ...options },
+        responseType: 'blob',
+    });
+    const disposition: string = response.headers['content-disposition'] ?? '';
+    const filename = /filename="([^"]+)"/.exec(disposition)?.[1] ?? `${dataset}.${options.format ?? 'csv'}`;
+    const url = URL.createObjectURL(response.data);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = filename;
+    link.click();
+    URL.revokeObjectURL(url);
+};
+
+export interface CategorySummary {
+    category_id?: string;
+    category: string;
+    type: 'income' | 'expense';
+    total: number;
+    count: number;
+}
+
+export interface SummaryBucket {
+    start: string;
+    income: number;
+    expense: number;
+    net: number;
+    balance: number;
+    count: number;
+}
+
+export interface Summary {
+    from?: string;
+    to?: string;
+    timezone: string;
+    interval: 'day' | 'week' | 'month';
+    opening_balance: number;
+    total_income: number;
+    total_expense: number;
+    balance: number;
+    closing_balance: number;
+    transaction_count: number;
+    categories: CategorySummary[];
+    series: SummaryBucket[];
+}
+
+export interface SummaryQuery {
+    from?: string;
+    to?: string;
+    tz?: string;
+    interval?: 'day' | 'week' | 'month';
+}
+
+export interface ReportQuery {
+    year?: number;
+    month?: number;
+    from?: string;
+    to?: string;
+    tz?: string;
+}
+
+// cashReportUrl points at the printable laporan kas, for opening in a new tab.
+export const cashReportUrl = (query: ReportQuery = {}, format: 'html' | 'pdf' = 'html'): string => {
+    const params = new URLSearchParams({ format });
+    Object.entries(query).forEach(([key, value]) => {
+        if (value !== undefined) params.set(key, String(value));
+    });
+    return `/api/reports/cash?${params}`;
+};
+
+export const getSummary = async (query: SummaryQuery = {}): Promise<Summary> => {
+    const response = await api.get<Summary>('/summary', { params: query });
     return response.data;
 };
 
@@ -84,6 +219,58 @@ export const deleteTransaction = async (id: string): Promise<void> => {
     await api.delete(`/transactions/${id}`);
 };
 
+export const getCategories = async (): Promise<Category[]> => {
+    const response = await api.get<Category[]>('/categories');
+    return response.data;
+};
+
+export const createCategory = async (category: Partial<Category>): Promise<Category> => {
+    const response = await api.post<Category>('/categories', category);
+    return response.data;
+};
+
+export const updateCategory = async (id: string, category: Partial<Category>): Promise<Category> => {
+    const response = await api.put<Category>(`/categories/${id}`, category);
+    return response.data;
+};
+
+export const deleteCategory = async (id: string): Promise<void> => {
+    await api.delete(`/categories/${id}`);
+};
+
+export interface ClosedPeriod {
+    id: string;
+    start: string;
+    end: string;
+    label: string;
+    opening_balance: number;
+    total_income: number;
+    total_expense: number;
+    closing_balance: number;
+    transaction_count: number;
+    note?: string;
+    closed_at: string;
+    closed_by_username?: string;
+    reopened_at?: string;
+    reopened_by_username?: string;
+    reopen_reason?: string;
+}
+
+export const getPeriods = async (): Promise<ClosedPeriod[]> => {
+    const response = await api.get<ClosedPeriod[]>('/periods');
+    return response.data;
+};
+
+export const closePeriod = async (year: number, month: number, note = ''): Promise<ClosedPeriod> => {
+    const response = await api.post<ClosedPeriod>('/periods/close', { year, month, note });
+    return response.data;
+};
+
+export const reopenPeriod = async (id: string, reason: string): Promise<ClosedPeriod> => {
+    const response = await api.post<ClosedPeriod>(`/periods/${id}/reopen`, { reason });
+    return response.data;
+};
+
 export const getUsers = async (): Promise<User[]> => {
     const response = await api.get<User[]>('/users');
     return response.data;
@@ -95,6 +282,10 @@ export interface AppSettings {
     kelurahan: string;
     kecamatan: string;
     address: string;
+    ketua_rt_name?: string;
+    bendahara_name?: string;
+    max_backdate_days?: number;
+    immutable_ledger?: boolean;
 }
 
 export const getSettings = async (): Promise<AppSettings> => {
@@ -115,6 +306,10 @@ export interface AuditLog {
     note: string;
     created_at: string;
     created_by?: string;
+    created_by_username?: string;
+    created_by_name?: string;
+    ip_address?: string;
+    user_agent?: string;
 }
 
 export const getAuditLogs = async (): Promise<AuditLog[]> => {
@@ -4,3 +4,21 @@ import { twMerge } from "tailwind-merge";
 export function cn(...inputs: ClassValue[]) {
     return twMerge(clsx(inputs));
 }
+
+// transactionDate is the date a transaction happened, falling back to when
+// it was entered for older records.
+export function transactionDate(tx: { transaction_date?: string; created_at: string }): Date {
+    return new Date(tx.transaction_date ?? tx.created_at);
+}
+
+// dateInputValue formats d as YYYY-MM-DD in local time, for <input type="date">.
+export function dateInputValue(d: Date = new Date()): string {
+    const pad = (n: number) => String(n).padStart(2, '0');
+    return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())}`;
+}
+
+// cashFlow is the transaction's effect on the cash balance. Reversing
+// entries have negative amounts, so an income reversal is money out.
+export function cashFlow(tx: { type: 'income' | 'expense'; amount: number }): number {
+    return tx.type === 'income' ? tx.amount : -tx.amount;
+}
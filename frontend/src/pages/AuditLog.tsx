@@ -117,7 +117,7 @@ const AuditLog = () => {
                                                 <td className="px-4 py-3 capitalize">{log.entity_type}</td>
                                                 <td className="px-4 py-3 font-mono text-xs">{log.entity_id}</td>
                                                 <td className="px-4 py-3 max-w-xs truncate" title={log.note}>{log.note || '-'}</td>
-                                                <td className="px-4 py-3">{log.created_by || 'System'}</td>
+                                                <td className="px-4 py-3" title={log.ip_address ? `IP: ${log.ip_address}` : undefined}>{log.created_by_name || log.created_by_username || 'System'}</td>
                                                 <td className="px-4 py-3 text-right">
                                                     {['update', 'delete', 'correction'].includes(log.action.toLowerCase()) && (
                                                         <button
@@ -1,7 +1,9 @@
 import { useEffect, useState } from "react";
 import { Card, CardHeader, CardTitle, CardContent } from "../components/ui/Card";
-import { Receipt, Users, ArrowUpRight, ArrowDownRight, Activity } from "lucide-react";
-import { getTransactions, getUsers, type Transaction } from "../lib/api";
+import { Receipt, Users, ArrowUpRight, ArrowDownRight, Activity, FileText } from "lucide-react";
+import { Button } from "../components/ui/Button";
+import { cashReportUrl, getSummary, getTransactions, getUsers, type Transaction } from "../lib/api";
+import { cashFlow } from "../lib/utils";
 import { AreaChart, Area, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer } from 'recharts';
 import { motion } from "framer-motion";
 
@@ -16,41 +18,22 @@ export default function Dashboard() {
     const [chartData, setChartData] = useState<any[]>([]);
 
     useEffect(() => {
-        Promise.all([getTransactions(), getUsers()]).then(([txsData, usersData]) => {
-            const txs = Array.isArray(txsData) ? txsData : [];
+        Promise.all([getSummary({ interval: 'day' }), getTransactions({ limit: 5 }), getUsers()]).then(([summary, txsData, usersData]) => {
             const users = Array.isArray(usersData) ? usersData : [];
 
-            setTransactions(txs);
+            setTransactions(Array.isArray(txsData.data) ? txsData.data : []);
             setUserCount(users.length);
 
-            let income = 0;
-            let expense = 0;
-            const sortedTxs = [...txs].sort((a, b) => new Date(a.created_at).getTime() - new Date(b.created_at).getTime());
-
-            const runningBalance: any[] = [];
-            let currentBalance = 0;
-
-            sortedTxs.forEach(tx => {
-                if (tx.type === 'income') {
-                    income += tx.amount;
-                    currentBalance += tx.amount;
-                } else {
-                    expense += tx.amount;
-                    currentBalance -= tx.amount;
-                }
-                runningBalance.push({
-                    date: new Date(tx.created_at).toLocaleDateString("id-ID"),
-                    balance: currentBalance,
-                    amount: tx.amount
-                });
-            });
-
             setStats({
-                totalBalance: income - expense,
-                income,
-                expense
+                totalBalance: summary.closing_balance,
+                income: summary.total_income,
+                expense: summary.total_expense
             });
-            setChartData(runningBalance.slice(-20));
+            setChartData(summary.series.slice(-20).map(bucket => ({
+                date: new Date(bucket.start).toLocaleDateString("id-ID"),
+                balance: bucket.balance,
+                amount: bucket.net
+            })));
         });
     }, []);
 
@@ -84,6 +67,15 @@ export default function Dashboard() {
                 <motion.h1 variants={itemVariants} className="text-3xl font-bold tracking-tight bg-clip-text text-transparent bg-gradient-to-r from-primary to-accent">
                     Dashboard Overview
                 </motion.h1>
+                <motion.div variants={itemVariants} className="flex gap-2">
+                    <Button variant="outline" onClick={() => window.open(cashReportUrl(), '_blank')}>
+                        <FileText className="mr-2 h-4 w-4" />
+                        Laporan Kas Bulan Ini
+                    </Button>
+                    <Button variant="outline" onClick={() => window.open(cashReportUrl({}, 'pdf'), '_blank')}>
+                        PDF
+                    </Button>
+                </motion.div>
             </div>
 
             <div className="grid gap-6 md:grid-cols-2 lg:grid-cols-4">
@@ -176,7 +168,7 @@ export default function Dashboard() {
                         </CardHeader>
                         <CardContent>
                             <div className="space-y-6">
-                                {transactions.slice(-5).reverse().map((tx) => (
+                                {transactions.map((tx) => (
                                     <div key={tx.id} className="flex items-center justify-between group">
                                         <div className="flex items-center gap-4">
                                             <div className={`h-10 w-10 rounded-full flex items-center justify-center border transition-colors ${tx.type === 'income' ? 'bg-green-500/10 border-green-500/20 text-green-500' : 'bg-red-500/10 border-red-500/20 text-red-500'
@@ -188,9 +180,9 @@ export default function Dashboard() {
                                                 <p className="text-xs text-muted-foreground" title={tx.description}>{tx.description || '-'}</p>
                                             </div>
                                         </div>
-                                        <div className={`font-medium px-2 py-1 rounded text-xs ${tx.type === 'income' ? 'text-green-500 bg-green-500/10' : 'text-red-500 bg-red-500/10'
+                                        <div className={`font-medium px-2 py-1 rounded text-xs ${cashFlow(tx) >= 0 ? 'text-green-500 bg-green-500/10' : 'text-red-500 bg-red-500/10'
                                             }`}>
-                                            {tx.type === 'income' ? '+' : '-'}{formatCurrency(tx.amount)}
+                                            {cashFlow(tx) >= 0 ? '+' : '-'}{formatCurrency(Math.abs(tx.amount))}
                                         </div>
                                     </div>
                                 ))}
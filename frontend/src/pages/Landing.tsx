@@ -1,8 +1,9 @@
 import { useEffect, useState } from "react";
 import { Card, CardHeader, CardTitle, CardContent } from "../components/ui/Card";
-import { getTransactions, type Transaction } from "../lib/api";
+import { getSummary, getTransactions, type Transaction } from "../lib/api";
 import { Receipt } from "lucide-react";
 import { motion } from "framer-motion";
+import { transactionDate, cashFlow } from "../lib/utils";
 
 export default function Landing() {
     const [stats, setStats] = useState({
@@ -13,27 +14,16 @@ export default function Landing() {
     const [transactions, setTransactions] = useState<Transaction[]>([]);
 
     useEffect(() => {
-        getTransactions().then((txsData) => {
-            const txs = Array.isArray(txsData) ? txsData : [];
-            let income = 0;
-            let expense = 0;
-            const sortedTxs = [...txs].sort((a, b) => new Date(a.created_at).getTime() - new Date(b.created_at).getTime());
-
-            sortedTxs.forEach(tx => {
-                if (tx.type === 'income') {
-                    income += tx.amount;
-                } else {
-                    expense += tx.amount;
-                }
-            });
-
+        getSummary().then((summary) => {
             setStats({
-                totalBalance: income - expense,
-                income,
-                expense
+                totalBalance: summary.closing_balance,
+                income: summary.total_income,
+                expense: summary.total_expense
             });
-            setTransactions(txs);
-        });
+        }).catch(console.error);
+        getTransactions({ limit: 10 }).then((res) => {
+            setTransactions(Array.isArray(res.data) ? res.data : []);
+        }).catch(console.error);
     }, []);
 
     const formatCurrency = (amount: number) => {
@@ -121,7 +111,7 @@ export default function Landing() {
                 </div>
 
                 <div className="space-y-4">
-                    {transactions.slice(-10).reverse().map((tx) => (
+                    {transactions.map((tx) => (
                         <Card key={tx.id} className="p-4 hover:bg-accent/5 transition-colors">
                             <div className="flex items-center justify-between gap-4">
                                 <div className="flex items-center gap-3 md:gap-4 min-w-0 flex-1">
@@ -131,11 +121,11 @@ export default function Landing() {
                                     </div>
                                     <div className="min-w-0 flex-1">
                                         <h3 className="font-semibold text-base md:text-lg truncate">{tx.category}</h3>
-                                        <p className="text-xs md:text-sm text-muted-foreground truncate">{tx.description} • {new Date(tx.created_at).toLocaleDateString("id-ID")}</p>
+                                        <p className="text-xs md:text-sm text-muted-foreground truncate">{tx.description} • {transactionDate(tx).toLocaleDateString("id-ID")}</p>
                                     </div>
                                 </div>
-                                <div className={`text-base md:text-xl font-bold whitespace-nowrap ${tx.type === 'income' ? 'text-green-500' : 'text-red-500'}`}>
-                                    {tx.type === 'income' ? '+' : '-'}{formatCurrency(tx.amount)}
+                                <div className={`text-base md:text-xl font-bold whitespace-nowrap ${cashFlow(tx) >= 0 ? 'text-green-500' : 'text-red-500'}`}>
+                                    {cashFlow(tx) >= 0 ? '+' : '-'}{formatCurrency(Math.abs(tx.amount))}
                                 </div>
                             </div>
                         </Card>
@@ -6,6 +6,8 @@ import { Label } from "../components/ui/Label";
 import { getSettings, updateSettings, type AppSettings } from "../lib/api";
 import { Save } from "lucide-react";
 import { motion } from "framer-motion";
+import CategoryManager from "../components/CategoryManager";
+import PeriodManager from "../components/PeriodManager";
 
 export default function SettingsPage() {
     const [settings, setSettings] = useState<AppSettings>({
@@ -13,7 +15,10 @@ export default function SettingsPage() {
         rw_name: "",
         kelurahan: "",
         kecamatan: "",
-        address: ""
+        address: "",
+        ketua_rt_name: "",
+        bendahara_name: "",
+        max_backdate_days: 0
     });
     const [loading, setLoading] = useState(false);
     const [message, setMessage] = useState<{ text: string, type: 'success' | 'error' } | null>(null);
@@ -139,6 +144,61 @@ export default function SettingsPage() {
                                         disabled={!isAdmin()}
                                     />
                                 </div>
+                                <div className="grid grid-cols-2 gap-4">
+                                    <div className="space-y-2">
+                                        <Label htmlFor="ketua_rt_name">Nama Ketua RT</Label>
+                                        <Input
+                                            id="ketua_rt_name"
+                                            name="ketua_rt_name"
+                                            value={settings.ketua_rt_name ?? ""}
+                                            onChange={handleChange}
+                                            placeholder="Untuk tanda tangan laporan"
+                                            className="bg-background/50"
+                                            disabled={!isAdmin()}
+                                        />
+                                    </div>
+                                    <div className="space-y-2">
+                                        <Label htmlFor="bendahara_name">Nama Bendahara</Label>
+                                        <Input
+                                            id="bendahara_name"
+                                            name="bendahara_name"
+                                            value={settings.bendahara_name ?? ""}
+                                            onChange={handleChange}
+                                            placeholder="Untuk tanda tangan laporan"
+                                            className="bg-background/50"
+                                            disabled={!isAdmin()}
+                                        />
+                                    </div>
+                                </div>
+
+                                <div className="space-y-2">
+                                    <Label htmlFor="max_backdate_days">Batas Tanggal Mundur (hari)</Label>
+                                    <Input
+                                        id="max_backdate_days"
+                                        name="max_backdate_days"
+                                        type="number"
+                                        min={0}
+                                        value={settings.max_backdate_days ?? 0}
+                                        onChange={e => setSettings({ ...settings, max_backdate_days: Number(e.target.value) })}
+                                        className="bg-background/50"
+                                        disabled={!isAdmin()}
+                                    />
+                                    <p className="text-xs text-muted-foreground">Transaksi tidak boleh bertanggal lebih dari sekian hari sebelum hari ini. Isi 0 untuk tanpa batas; impor riwayat tidak dibatasi.</p>
+                                </div>
+
+                                <div className="space-y-2">
+                                    <label className="flex items-center gap-2 text-sm font-medium cursor-pointer">
+                                        <input
+                                            type="checkbox"
+                                            checked={settings.immutable_ledger ?? false}
+                                            onChange={e => setSettings({ ...settings, immutable_ledger: e.target.checked })}
+                                            className="accent-primary"
+                                            disabled={!isAdmin()}
+                                        />
+                                        Buku kas tidak dapat diubah (jurnal pembalik)
+                                    </label>
+                                    <p className="text-xs text-muted-foreground">Mengedit transaksi mencatat jurnal pembalik dan transaksi koreksi baru pada hari ini; menghapus hanya mencatat jurnal pembalik. Transaksi yang sudah tercatat tidak pernah diubah.</p>
+                                </div>
 
                                 {message && (
                                     <motion.div
@@ -291,6 +351,22 @@ export default function SettingsPage() {
                     </Card>
                 </motion.div>
             </div>
+
+            <motion.div
+                initial={{ y: 20, opacity: 0 }}
+                animate={{ y: 0, opacity: 1 }}
+                transition={{ delay: 0.3 }}
+            >
+                <CategoryManager isAdmin={isAdmin()} />
+            </motion.div>
+
+            <motion.div
+                initial={{ y: 20, opacity: 0 }}
+                animate={{ y: 0, opacity: 1 }}
+                transition={{ delay: 0.4 }}
+            >
+                <PeriodManager isAdmin={isAdmin()} />
+            </motion.div>
         </motion.div>
     );
 }
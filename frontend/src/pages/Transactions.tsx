@@ -2,24 +2,39 @@ import { useEffect, useState } from "react";
 import { useToast } from "../components/ui/use-toast";
 import { Button } from "../components/ui/Button";
 import { Card, CardHeader, CardTitle, CardContent } from "../components/ui/Card";
-import { Plus, X, Pencil, Trash2 } from "lucide-react";
-import { getTransactions, updateTransaction, deleteTransaction, type Transaction, default as api } from "../lib/api";
+import { Plus, X, Pencil, Trash2, ChevronLeft, ChevronRight, Download, Lock, FilePlus } from "lucide-react";
+import { getTransactions, downloadExport, updateTransaction, deleteTransaction, getCategories, getPeriods, getSettings, type Transaction, type Category, type ClosedPeriod, type TransactionQuery, default as api } from "../lib/api";
 import { Input } from "../components/ui/Input";
 import { Label } from "../components/ui/Label";
+import { dateInputValue, transactionDate, cashFlow } from "../lib/utils";
 import { motion, AnimatePresence } from "framer-motion";
 
+const PAGE_SIZE = 25;
+
+const emptyForm = () => ({
+    id: '',
+    type: 'expense',
+    amount: '',
+    category_id: '',
+    description: '',
+    transaction_date: dateInputValue(),
+    adjustment_of: ''
+});
+
 export default function Transactions() {
     const { toast } = useToast();
     const [transactions, setTransactions] = useState<Transaction[]>([]);
+    const [page, setPage] = useState(1);
+    const [pages, setPages] = useState(0);
+    const [total, setTotal] = useState(0);
+    const [search, setSearch] = useState('');
+    const [typeFilter, setTypeFilter] = useState<'' | 'income' | 'expense'>('');
+    const [categories, setCategories] = useState<Category[]>([]);
     const [isModalOpen, setIsModalOpen] = useState(false);
     const [loading, setLoading] = useState(false);
-    const [formData, setFormData] = useState({
-        id: '',
-        type: 'expense',
-        amount: '',
-        category: '',
-        description: ''
-    });
+    const [periods, setPeriods] = useState<ClosedPeriod[]>([]);
+    const [immutable, setImmutable] = useState(false);
+    const [formData, setFormData] = useState(emptyForm());
 
     const [editingId, setEditingId] = useState<string | null>(null);
     const isAdmin = () => {
@@ -34,15 +49,51 @@ export default function Transactions() {
     };
 
     const fetchTransactions = () => {
-        getTransactions().then((data) => {
-            setTransactions(Array.isArray(data) ? data : []);
-        });
+        const query: TransactionQuery = { page, limit: PAGE_SIZE };
+        if (search.trim()) query.q = search.trim();
+        if (typeFilter) query.type = typeFilter;
+        getTransactions(query).then((res) => {
+            setTransactions(Array.isArray(res.data) ? res.data : []);
+            setPages(res.pages);
+            setTotal(res.total);
+        }).catch(console.error);
+    };
+
+    const handleExport = async () => {
+        const query: TransactionQuery = {};
+        if (search.trim()) query.q = search.trim();
+        if (typeFilter) query.type = typeFilter;
+        try {
+            await downloadExport('transactions', query, { format: 'csv' });
+        } catch (error) {
+            console.error(error);
+            toast({
+                variant: "destructive",
+                title: "Gagal",
+                description: "Ekspor transaksi gagal"
+            });
+        }
+    };
+
+    // lockedPeriod is the closed period a transaction falls in, if any.
+    const lockedPeriod = (tx: Transaction) => {
+        const date = transactionDate(tx);
+        return periods.find(p => !p.reopened_at && new Date(p.start) <= date && date < new Date(p.end));
     };
 
     useEffect(() => {
-        fetchTransactions();
+        getPeriods().then(setPeriods).catch(console.error);
+        if (localStorage.getItem('token')) {
+            getCategories().then(setCategories).catch(console.error);
+            getSettings().then(s => setImmutable(!!s.immutable_ledger)).catch(console.error);
+        }
     }, []);
 
+    useEffect(() => {
+        const timer = setTimeout(fetchTransactions, 300);
+        return () => clearTimeout(timer);
+    }, [page, search, typeFilter]);
+
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
         setLoading(true);
@@ -71,14 +122,17 @@ export default function Transactions() {
             }
             setIsModalOpen(false);
             setEditingId(null);
-            setFormData({ id: '', type: 'expense', amount: '', category: '', description: '' });
+            setFormData(emptyForm());
             fetchTransactions();
         } catch (error) {
             console.error(error);
+            const data = (error as { response?: { data?: { error?: string, errors?: { message: string }[] } } }).response?.data;
             toast({
                 variant: "destructive",
                 title: "Gagal",
-                description: editingId ? "Gagal memperbarui transaksi" : "Gagal menyimpan transaksi"
+                description: data?.errors?.length
+                    ? data.errors.map(e => e.message).join(", ")
+                    : data?.error ?? (editingId ? "Gagal memperbarui transaksi" : "Gagal menyimpan transaksi")
             });
         } finally {
             setLoading(false);
@@ -91,21 +145,40 @@ export default function Transactions() {
             id: tx.id,
             type: tx.type,
             amount: String(tx.amount),
-            category: tx.category,
-            description: tx.description
+            category_id: tx.category_id ?? '',
+            description: tx.description,
+            transaction_date: dateInputValue(transactionDate(tx)),
+            adjustment_of: ''
+        });
+        setIsModalOpen(true);
+    };
+
+    // handleAdjust opens a new adjusting entry for a transaction in a closed
+    // period, dated today.
+    const handleAdjust = (tx: Transaction) => {
+        setEditingId(null);
+        setFormData({
+            ...emptyForm(),
+            type: tx.type,
+            category_id: tx.category_id ?? '',
+            description: `Penyesuaian: ${tx.description}`,
+            adjustment_of: tx.id
         });
         setIsModalOpen(true);
     };
 
     const handleDelete = async (id: string) => {
-        if (!window.confirm("Apakah Anda yakin ingin menghapus transaksi ini?")) return;
+        const question = immutable
+            ? "Transaksi ini akan dibatalkan dengan jurnal pembalik. Lanjutkan?"
+            : "Apakah Anda yakin ingin menghapus transaksi ini?";
+        if (!window.confirm(question)) return;
 
         try {
             await deleteTransaction(id);
             toast({
                 variant: "success",
                 title: "Berhasil",
-                description: "Transaksi berhasil dihapus"
+                description: immutable ? "Transaksi berhasil dibatalkan" : "Transaksi berhasil dihapus"
             });
             fetchTransactions();
         } catch (error) {
@@ -113,7 +186,7 @@ export default function Transactions() {
             toast({
                 variant: "destructive",
                 title: "Gagal",
-                description: "Gagal menghapus transaksi"
+                description: (error as { response?: { data?: { error?: string } } }).response?.data?.error ?? "Gagal menghapus transaksi"
             });
         }
     };
@@ -136,21 +209,28 @@ export default function Transactions() {
                 >
                     Data Transaksi
                 </motion.h1>
-                {isAdmin() && (
-                    <motion.div
-                        initial={{ x: 20, opacity: 0 }}
-                        animate={{ x: 0, opacity: 1 }}
-                    >
+                <motion.div
+                    initial={{ x: 20, opacity: 0 }}
+                    animate={{ x: 0, opacity: 1 }}
+                    className="flex gap-2"
+                >
+                    {localStorage.getItem('token') && (
+                        <Button variant="ghost" onClick={handleExport}>
+                            <Download className="mr-2 h-4 w-4" />
+                            Ekspor CSV
+                        </Button>
+                    )}
+                    {isAdmin() && (
                         <Button variant="neon" onClick={() => {
                             setEditingId(null);
-                            setFormData({ id: '', type: 'expense', amount: '', category: '', description: '' });
+                            setFormData(emptyForm());
                             setIsModalOpen(true);
                         }}>
                             <Plus className="mr-2 h-4 w-4" />
                             Transaksi Baru
                         </Button>
-                    </motion.div>
-                )}
+                    )}
+                </motion.div>
             </div>
 
             <motion.div
@@ -159,8 +239,25 @@ export default function Transactions() {
                 transition={{ delay: 0.1 }}
             >
                 <Card className="glass-card border-none shadow-lg overflow-hidden">
-                    <CardHeader>
+                    <CardHeader className="flex flex-col gap-4 md:flex-row md:items-center md:justify-between">
                         <CardTitle>Riwayat Transaksi</CardTitle>
+                        <div className="flex gap-2">
+                            <Input
+                                placeholder="Cari keterangan atau kategori..."
+                                value={search}
+                                onChange={e => { setSearch(e.target.value); setPage(1); }}
+                                className="md:w-64"
+                            />
+                            <select
+                                value={typeFilter}
+                                onChange={e => { setTypeFilter(e.target.value as '' | 'income' | 'expense'); setPage(1); }}
+                                className="flex h-10 rounded-md border border-input bg-background/50 px-3 py-2 text-sm"
+                            >
+                                <option value="">Semua</option>
+                                <option value="income">Masuk</option>
+                                <option value="expense">Keluar</option>
+                            </select>
+                        </div>
                     </CardHeader>
                     <CardContent className="p-0">
                         <div className="border-t border-white/10 overflow-x-auto">
@@ -176,7 +273,7 @@ export default function Transactions() {
                                     </tr>
                                 </thead>
                                 <tbody>
-                                    {transactions.slice().reverse().map((tx, index) => (
+                                    {transactions.map((tx, index) => (
                                         <motion.tr
                                             key={tx.id}
                                             initial={{ opacity: 0, x: -10 }}
@@ -184,8 +281,16 @@ export default function Transactions() {
                                             transition={{ delay: index * 0.05 }}
                                             className="border-b border-white/5 transition-colors hover:bg-primary/5"
                                         >
-                                            <td className="p-4 align-middle whitespace-nowrap">{new Date(tx.created_at).toLocaleDateString("id-ID")}</td>
-                                            <td className="p-4 align-middle min-w-[200px]">{tx.description}</td>
+                                            <td className="p-4 align-middle whitespace-nowrap">
+                                                {transactionDate(tx).toLocaleDateString("id-ID")}
+                                                {lockedPeriod(tx) && <Lock className="inline ml-1 h-3 w-3 text-muted-foreground" aria-label="Periode sudah ditutup" />}
+                                            </td>
+                                            <td className="p-4 align-middle min-w-[200px]">
+                                                {tx.description}
+                                                {tx.reversal_of && <span className="ml-2 text-xs text-muted-foreground" title={`Membalik transaksi ${tx.reversal_of}`}>(pembalik)</span>}
+                                                {tx.correction_of && <span className="ml-2 text-xs text-muted-foreground" title={`Koreksi atas transaksi ${tx.correction_of}`}>(koreksi)</span>}
+                                                {tx.adjustment_of && <span className="ml-2 text-xs text-muted-foreground" title={`Penyesuaian atas transaksi ${tx.adjustment_of}`}>(penyesuaian)</span>}
+                                            </td>
                                             <td className="p-4 align-middle">
                                                 <span className={`inline-flex items-center rounded-full border px-2.5 py-0.5 text-xs font-semibold transition-colors focus:outline-none focus:ring-2 focus:ring-ring focus:ring-offset-2 border-transparent ${tx.type === 'income' ? 'bg-green-500/10 text-green-500' : 'bg-red-500/10 text-red-500'
                                                     }`}>
@@ -193,20 +298,26 @@ export default function Transactions() {
                                                 </span>
                                             </td>
                                             <td className="p-4 align-middle text-muted-foreground">Admin</td>
-                                            <td className={`p-4 align-middle text-right font-bold whitespace-nowrap ${tx.type === 'income' ? 'text-green-500' : 'text-red-500'
+                                            <td className={`p-4 align-middle text-right font-bold whitespace-nowrap ${cashFlow(tx) >= 0 ? 'text-green-500' : 'text-red-500'
                                                 }`}>
-                                                {tx.type === 'income' ? '+' : '-'}{formatCurrency(tx.amount)}
+                                                {cashFlow(tx) >= 0 ? '+' : '-'}{formatCurrency(Math.abs(tx.amount))}
                                             </td>
                                             {isAdmin() && (
                                                 <td className="p-4 align-middle text-center">
-                                                    <div className="flex items-center justify-center gap-2">
-                                                        <Button variant="ghost" size="icon" onClick={() => handleEdit(tx)} className="h-8 w-8 text-blue-400 hover:text-blue-300 hover:bg-blue-400/10">
-                                                            <Pencil className="h-4 w-4" />
+                                                    {immutable && tx.reversal_of ? null : lockedPeriod(tx) && !immutable ? (
+                                                        <Button variant="ghost" size="sm" onClick={() => handleAdjust(tx)} title="Buat transaksi penyesuaian">
+                                                            <FilePlus className="mr-1 h-4 w-4" /> Penyesuaian
                                                         </Button>
-                                                        <Button variant="ghost" size="icon" onClick={() => handleDelete(tx.id)} className="h-8 w-8 text-red-500 hover:text-red-400 hover:bg-red-500/10">
-                                                            <Trash2 className="h-4 w-4" />
-                                                        </Button>
-                                                    </div>
+                                                    ) : (
+                                                        <div className="flex items-center justify-center gap-2">
+                                                            <Button variant="ghost" size="icon" onClick={() => handleEdit(tx)} className="h-8 w-8 text-blue-400 hover:text-blue-300 hover:bg-blue-400/10">
+                                                                <Pencil className="h-4 w-4" />
+                                                            </Button>
+                                                            <Button variant="ghost" size="icon" onClick={() => handleDelete(tx.id)} className="h-8 w-8 text-red-500 hover:text-red-400 hover:bg-red-500/10">
+                                                                <Trash2 className="h-4 w-4" />
+                                                            </Button>
+                                                        </div>
+                                                    )}
                                                 </td>
                                             )}
                                         </motion.tr>
@@ -219,6 +330,19 @@ export default function Transactions() {
                                 </tbody>
                             </table>
                         </div>
+                        {pages > 1 && (
+                            <div className="flex items-center justify-between p-4 text-sm text-muted-foreground">
+                                <span>Halaman {page} dari {pages} ({total} transaksi)</span>
+                                <div className="flex gap-2">
+                                    <Button variant="ghost" size="icon" disabled={page <= 1} onClick={() => setPage(page - 1)} className="h-8 w-8">
+                                        <ChevronLeft className="h-4 w-4" />
+                                    </Button>
+                                    <Button variant="ghost" size="icon" disabled={page >= pages} onClick={() => setPage(page + 1)} className="h-8 w-8">
+                                        <ChevronRight className="h-4 w-4" />
+                                    </Button>
+                                </div>
+                            </div>
+                        )}
                     </CardContent>
                 </Card>
             </motion.div>
@@ -240,32 +364,46 @@ export default function Transactions() {
                             <button onClick={() => setIsModalOpen(false)} className="absolute top-4 right-4 text-muted-foreground hover:text-foreground">
                                 <X className="h-4 w-4" />
                             </button>
-                            <h2 className="text-xl font-bold mb-4">{editingId ? 'Edit Transaksi' : 'Tambah Transaksi'}</h2>
+                            <h2 className="text-xl font-bold mb-4">{editingId ? 'Edit Transaksi' : formData.adjustment_of ? 'Transaksi Penyesuaian' : 'Tambah Transaksi'}</h2>
+                            {formData.adjustment_of && (
+                                <p className="text-sm text-muted-foreground mb-4">Koreksi untuk transaksi di periode yang sudah ditutup. Catat selisihnya sebagai pemasukan atau pengeluaran di periode berjalan.</p>
+                            )}
                             <form onSubmit={handleSubmit} className="space-y-4">
                                 <div className="space-y-2">
                                     <Label>Jenis Transaksi</Label>
                                     <div className="flex gap-4">
                                         <label className="flex items-center gap-2 cursor-pointer">
-                                            <input type="radio" name="type" value="income" checked={formData.type === 'income'} onChange={e => setFormData({ ...formData, type: e.target.value })} className="accent-primary" />
+                                            <input type="radio" name="type" value="income" checked={formData.type === 'income'} onChange={e => setFormData({ ...formData, type: e.target.value, category_id: '' })} className="accent-primary" />
                                             Pemasukan
                                         </label>
                                         <label className="flex items-center gap-2 cursor-pointer">
-                                            <input type="radio" name="type" value="expense" checked={formData.type === 'expense'} onChange={e => setFormData({ ...formData, type: e.target.value })} className="accent-primary" />
+                                            <input type="radio" name="type" value="expense" checked={formData.type === 'expense'} onChange={e => setFormData({ ...formData, type: e.target.value, category_id: '' })} className="accent-primary" />
                                             Pengeluaran
                                         </label>
                                     </div>
                                 </div>
+                                <div className="space-y-2">
+                                    <Label htmlFor="transaction_date">Tanggal Transaksi</Label>
+                                    <Input id="transaction_date" type="date" required max={dateInputValue()} value={formData.transaction_date} onChange={e => setFormData({ ...formData, transaction_date: e.target.value })} className="bg-background/50" />
+                                </div>
                                 <div className="space-y-2">
                                     <Label htmlFor="amount">Jumlah (Rp)</Label>
                                     <Input id="amount" type="number" required value={formData.amount} onChange={e => setFormData({ ...formData, amount: e.target.value })} className="bg-background/50" />
                                 </div>
                                 <div className="space-y-2">
                                     <Label htmlFor="category">Kategori</Label>
-                                    <Input id="category" type="text" placeholder="Contoh: Iuran Warga, Listrik" required value={formData.category} onChange={e => setFormData({ ...formData, category: e.target.value })} className="bg-background/50" />
+                                    <select id="category" required value={formData.category_id} onChange={e => setFormData({ ...formData, category_id: e.target.value })} className="flex h-10 w-full rounded-md border border-input bg-background/50 px-3 py-2 text-sm">
+                                        <option value="" disabled>Pilih kategori</option>
+                                        {categories
+                                            .filter(c => c.kind === formData.type && (c.active || c.id === formData.category_id))
+                                            .map(c => (
+                                                <option key={c.id} value={c.id}>{c.name}</option>
+                                            ))}
+                                    </select>
                                 </div>
                                 <div className="space-y-2">
                                     <Label htmlFor="desc">Keterangan</Label>
-                                    <Input id="desc" type="text" placeholder="Detail transaksi..." value={formData.description} onChange={e => setFormData({ ...formData, description: e.target.value })} className="bg-background/50" />
+                                    <Input id="desc" type="text" maxLength={500} placeholder="Detail transaksi..." value={formData.description} onChange={e => setFormData({ ...formData, description: e.target.value })} className="bg-background/50" />
                                 </div>
                                 <div className="pt-4 flex justify-end gap-2">
                                     <Button type="button" variant="ghost" onClick={() => setIsModalOpen(false)}>Batal</Button>
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// actor identifies the authenticated user and client behind a request.
+func actor(c *fiber.Ctx) domain.Actor {
+	userID, _ := c.Locals("userID").(string)
+	username, _ := c.Locals("username").(string)
+	return domain.Actor{
+		UserID:    userID,
+		Username:  username,
+		IPAddress: c.IP(),
+		UserAgent: string(c.Request().Header.UserAgent()),
+	}
+}
+
+// update is Store.Update for request handlers: every audit entry written
+// through it is stamped with the request's actor.
+func (h *Handler) update(c *fiber.Ctx, fn func(t domain.Repositories) error) error {
+	a := actor(c)
+	return h.Store.Update(func(t domain.Repositories) error {
+		return fn(stampedRepositories{Repositories: t, actor: a})
+	})
+}
+
+type stampedRepositories struct {
+	domain.Repositories
+	actor domain.Actor
+}
+
+func (s stampedRepositories) InsertAuditLog(l domain.AuditLog) error {
+	l.Stamp(s.actor)
+	return s.Repositories.InsertAuditLog(l)
+}
+
+// auditLogView is an audit entry as returned by the API, with the author
+// resolved to a display name and the changed fields listed in one format
+// for current and legacy entries alike.
+type auditLogView struct {
+	domain.AuditLog
+	CreatedByName string                        `json:"created_by_name,omitempty"`
+	Changes       map[string]domain.FieldChange `json:"changes,omitempty"`
+}
+
+func auditLogViews(logs []domain.AuditLog, users []domain.User) []auditLogView {
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		name := u.FullName
+		if name == "" {
+			name = u.Username
+		}
+		names[u.ID] = name
+	}
+
+	views := make([]auditLogView, len(logs))
+	for i, l := range logs {
+		name, ok := names[l.CreatedBy]
+		if !ok {
+			name = l.CreatedByUsername
+		}
+		changes, err := l.Changes()
+		if err != nil {
+			log.Printf("Audit log %s: %v", l.ID, err)
+		}
+		views[i] = auditLogView{AuditLog: l, CreatedByName: name, Changes: changes}
+	}
+	return views
+}
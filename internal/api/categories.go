@@ -0,0 +1,224 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handler) GetCategories(c *fiber.Ctx) error {
+	return c.JSON(h.Store.Categories())
+}
+
+func (h *Handler) CreateCategory(c *fiber.Ctx) error {
+	var req domain.CategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("CreateCategory BodyParser error: %v", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	now := time.Now()
+	category := domain.Category{
+		ID:        domain.NewID(),
+		Kind:      req.Kind,
+		Code:      strings.ToUpper(strings.TrimSpace(req.Code)),
+		Name:      domain.NormalizeCategoryName(req.Name),
+		ParentID:  req.ParentID,
+		Active:    req.Active == nil || *req.Active,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if category.Code == "" {
+		category.Code = domain.CategoryCode(category.Name)
+	}
+
+	err := h.update(c, func(t domain.Repositories) error {
+		if err := domain.ValidateCategory(category, t.Categories()); err != nil {
+			return &httpError{400, err.Error()}
+		}
+		if err := t.InsertCategory(category); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "category",
+			EntityID:   category.ID,
+			Action:     "create",
+			Note:       fmt.Sprintf("Created %s category %s (%s)", category.Kind, category.Name, category.Code),
+			After:      domain.AuditState(category),
+			CreatedAt:  now,
+		})
+	})
+	if err != nil {
+		return updateError(c, "CreateCategory", err)
+	}
+
+	return c.JSON(category)
+}
+
+func (h *Handler) UpdateCategory(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "ID required"})
+	}
+
+	var req domain.CategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("UpdateCategory BodyParser error: %v", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	var category domain.Category
+	err := h.update(c, func(t domain.Repositories) error {
+		existing, found := t.Category(id)
+		if !found {
+			return &httpError{404, "Category not found"}
+		}
+		category = existing
+
+		var changes []string
+		if req.Kind != "" && req.Kind != category.Kind {
+			changes = append(changes, fmt.Sprintf("kind: %s -> %s", category.Kind, req.Kind))
+			category.Kind = req.Kind
+		}
+		if code := strings.ToUpper(strings.TrimSpace(req.Code)); code != "" && code != category.Code {
+			changes = append(changes, fmt.Sprintf("code: %s -> %s", category.Code, code))
+			category.Code = code
+		}
+		if name := domain.NormalizeCategoryName(req.Name); name != "" && name != category.Name {
+			changes = append(changes, fmt.Sprintf("name: %s -> %s", category.Name, name))
+			category.Name = name
+		}
+		if req.ParentID != category.ParentID && req.ParentID != "" {
+			changes = append(changes, fmt.Sprintf("parent: %s -> %s", category.ParentID, req.ParentID))
+			category.ParentID = req.ParentID
+		}
+		if req.Active != nil && *req.Active != category.Active {
+			changes = append(changes, fmt.Sprintf("active: %t -> %t", category.Active, *req.Active))
+			category.Active = *req.Active
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if err := domain.ValidateCategory(category, t.Categories()); err != nil {
+			return &httpError{400, err.Error()}
+		}
+
+		// Transactions keep the category name for display, so a rename or
+		// kind change has to be reflected in the transactions that use it.
+		var linked []domain.Transaction
+		for _, tx := range t.Transactions() {
+			if tx.CategoryID == id {
+				linked = append(linked, tx)
+			}
+		}
+		if category.Kind != existing.Kind && len(linked) > 0 {
+			return &httpError{409, "Cannot change the kind of a category that is used by transactions"}
+		}
+		if category.Name != existing.Name && len(linked) > 0 && t.Settings().ImmutableLedger {
+			return &httpError{409, "Cannot rename a category used by transactions in immutable-ledger mode"}
+		}
+		if category.Name != existing.Name {
+			periods := t.ClosedPeriods()
+			for _, tx := range linked {
+				if p, ok := domain.LockedPeriod(periods, tx.Date()); ok {
+					return &httpError{409, fmt.Sprintf("Cannot rename a category used by transactions in closed period %s", p.Label)}
+				}
+			}
+			for _, tx := range linked {
+				previous := tx
+				tx.Category = category.Name
+				tx.Touch(actor(c), time.Now())
+				if err := t.UpdateTransaction(tx); err != nil {
+					return err
+				}
+				if err := t.InsertAuditLog(domain.AuditLog{
+					EntityType: "transaction",
+					EntityID:   tx.ID,
+					Action:     "update",
+					Note:       fmt.Sprintf("category: %s -> %s (category renamed)", previous.Category, tx.Category),
+					Before:     domain.AuditState(previous),
+					After:      domain.AuditState(tx),
+					CreatedAt:  time.Now(),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+
+		category.UpdatedAt = time.Now()
+		if err := t.UpdateCategory(category); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "category",
+			EntityID:   category.ID,
+			Action:     "update",
+			Note:       strings.Join(changes, "; "),
+			Before:     domain.AuditState(existing),
+			After:      domain.AuditState(category),
+			CreatedAt:  time.Now(),
+		})
+	})
+	if err != nil {
+		return updateError(c, "UpdateCategory", err)
+	}
+
+	return c.JSON(category)
+}
+
+func (h *Handler) DeleteCategory(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "ID required"})
+	}
+
+	err := h.update(c, func(t domain.Repositories) error {
+		category, found := t.Category(id)
+		if !found {
+			return &httpError{404, "Category not found"}
+		}
+		for _, tx := range t.Transactions() {
+			if tx.CategoryID == id {
+				return &httpError{409, "Category is used by transactions; deactivate it instead"}
+			}
+		}
+		for _, child := range t.Categories() {
+			if child.ParentID == id {
+				return &httpError{409, "Category has subcategories"}
+			}
+		}
+
+		if err := t.DeleteCategory(id); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "category",
+			EntityID:   id,
+			Action:     "delete",
+			Note:       fmt.Sprintf("Deleted category %s (%s)", category.Name, category.Code),
+			Before:     domain.AuditState(category),
+			CreatedAt:  time.Now(),
+		})
+	})
+	if err != nil {
+		return updateError(c, "DeleteCategory", err)
+	}
+	return c.SendStatus(200)
+}
+
+// applyCategory resolves the category a transaction refers to and stores
+// both its ID and its current name on the transaction.
+func applyCategory(t domain.Repositories, tx *domain.Transaction) error {
+	category, err := domain.ResolveCategory(t.Categories(), tx.CategoryID, tx.Category, tx.Type)
+	if err != nil {
+		return domain.ValidationErrors{{Field: "category", Code: "invalid", Message: err.Error()}}
+	}
+	tx.CategoryID = category.ID
+	tx.Category = category.Name
+	return nil
+}
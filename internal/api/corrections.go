@@ -0,0 +1,63 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// In immutable-ledger mode posted transactions are never updated. An edit
+// posts a reversing entry and a corrected entry, a delete only the
+// reversing entry, all dated at the time of the correction and audited
+// with the correction action.
+
+// checkCorrectable rejects corrections of entries that are no longer part
+// of the ledger or that only exist to cancel another.
+func checkCorrectable(t domain.Repositories, tx domain.Transaction) error {
+	switch {
+	case tx.DeletedAt != nil:
+		return &httpError{409, "Transaction has been deleted"}
+	case tx.ReversalOf != "":
+		return &httpError{409, fmt.Sprintf("Reversing entries cannot be changed; correct transaction %s instead", tx.ReversalOf)}
+	case domain.Reversed(t.Transactions(), tx.ID):
+		return &httpError{409, "Transaction has already been reversed"}
+	}
+	return nil
+}
+
+func postReversal(t domain.Repositories, tx domain.Transaction, note string, a domain.Actor, now time.Time) (domain.Transaction, error) {
+	reversal := tx.Reverse(domain.NewID(), a, now)
+	if err := t.InsertTransaction(reversal); err != nil {
+		return reversal, err
+	}
+	return reversal, t.InsertAuditLog(domain.AuditLog{
+		EntityType: "transaction",
+		EntityID:   reversal.ID,
+		Action:     "correction",
+		Note:       note,
+		After:      domain.AuditState(reversal),
+		CreatedAt:  now,
+	})
+}
+
+// postCorrection replaces original with corrected, the edited copy
+// described by changes, and returns the new entry.
+func postCorrection(t domain.Repositories, original, corrected domain.Transaction, changes []string, a domain.Actor, now time.Time) (domain.Transaction, error) {
+	note := fmt.Sprintf("Reversed transaction %s for correction (Amount: %s)", original.ID, original.Amount)
+	if _, err := postReversal(t, original, note, a, now); err != nil {
+		return corrected, err
+	}
+	correction := original.Correct(corrected, domain.NewID(), a, now)
+	if err := t.InsertTransaction(correction); err != nil {
+		return correction, err
+	}
+	return correction, t.InsertAuditLog(domain.AuditLog{
+		EntityType: "transaction",
+		EntityID:   correction.ID,
+		Action:     "correction",
+		Note:       fmt.Sprintf("Corrected transaction %s: %s", original.ID, strings.Join(changes, "; ")),
+		After:      domain.AuditState(correction),
+		CreatedAt:  now,
+	})
+}
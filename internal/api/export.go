@@ -0,0 +1,148 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/export"
+	"audit-sendiri/internal/query"
+	"bufio"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportTransactions streams the transactions matching the listing filters
+// (from, to, tz, type, category, min_amount, max_amount, q, sort, order) as
+// CSV or NDJSON, or the from/to period as a Beancount or ledger-cli journal.
+// The export is audited before anything is sent.
+func (h *Handler) ExportTransactions(c *fiber.Ctx) error {
+	opts, err := exportOptions(c)
+	if err != nil {
+		return updateError(c, "ExportTransactions", err)
+	}
+	if opts.Journal() {
+		return h.exportJournal(c, opts)
+	}
+	stmt, _, _, err := query.Transactions(c.Query, h.Store.Categories(), false)
+	if err != nil {
+		return updateError(c, "ExportTransactions", err)
+	}
+	txs, _, err := h.Store.QueryTransactions(stmt)
+	if err != nil {
+		log.Printf("ExportTransactions query error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+
+	if err := h.auditExport(c, "transactions", opts, len(txs)); err != nil {
+		return updateError(c, "ExportTransactions", err)
+	}
+	return streamExport(c, "transaksi", opts, func(w *bufio.Writer) error {
+		return export.Transactions(w, txs, opts)
+	})
+}
+
+// ExportAuditLog streams the audit log entries matching from, to, tz,
+// entity_type, entity_id, action and user, oldest first.
+func (h *Handler) ExportAuditLog(c *fiber.Ctx) error {
+	opts, err := exportOptions(c)
+	if err != nil {
+		return updateError(c, "ExportAuditLog", err)
+	}
+	var errs domain.ValidationErrors
+	if opts.Journal() {
+		errs.Add("format", "invalid", "the audit log can only be exported as csv or ndjson")
+	}
+	filter := query.ParseAuditFilter(c.Query, &errs)
+	if len(errs) > 0 {
+		return updateError(c, "ExportAuditLog", errs)
+	}
+	logs := filter.Filter(h.Store.AuditLogs())
+
+	if err := h.auditExport(c, "audit_log", opts, len(logs)); err != nil {
+		return updateError(c, "ExportAuditLog", err)
+	}
+	return streamExport(c, "audit-log", opts, func(w *bufio.Writer) error {
+		return export.AuditLogs(w, logs, opts)
+	})
+}
+
+// exportJournal streams every live transaction of the from/to period with
+// monthly balance assertions.
+func (h *Handler) exportJournal(c *fiber.Ctx, opts export.Options) error {
+	var errs domain.ValidationErrors
+	r := query.ParseDateRangeOnly(c.Query, &errs)
+	if len(errs) > 0 {
+		return updateError(c, "ExportTransactions", errs)
+	}
+	// One snapshot, so the balance assertions and audit_hash metadata
+	// describe the same state.
+	var j *export.Journal
+	h.Store.View(func(s domain.Snapshot) error {
+		settings := s.Settings()
+		title := fmt.Sprintf("Buku Kas RT %s / RW %s", settings.RTName, settings.RWName)
+		j = export.NewJournal(title, s.Transactions(), s.Categories(), s.AuditLogs(), r.From, r.To, r.Location)
+		return nil
+	})
+
+	if err := h.auditExport(c, "transactions", opts, len(j.Entries)); err != nil {
+		return updateError(c, "ExportTransactions", err)
+	}
+	return streamExport(c, "buku-kas", opts, func(w *bufio.Writer) error {
+		return j.Write(w, opts.Format)
+	})
+}
+
+// exportOptions reads format (csv by default), decimal (point or comma),
+// bom (true by default for CSV) and tz.
+func exportOptions(c *fiber.Ctx) (export.Options, error) {
+	var errs domain.ValidationErrors
+	opts := export.Options{Format: c.Query("format", export.FormatCSV)}
+	if err := opts.Validate(); err != nil {
+		errs.Add("format", "invalid", err.Error())
+	}
+	switch c.Query("decimal", "point") {
+	case "point":
+	case "comma":
+		opts.DecimalComma = true
+	default:
+		errs.Add("decimal", "invalid", "decimal must be point or comma")
+	}
+	switch c.Query("bom", "true") {
+	case "true", "1":
+		opts.BOM = opts.Format == export.FormatCSV
+	case "false", "0":
+	default:
+		errs.Add("bom", "invalid", "bom must be true or false")
+	}
+	loc, err := time.LoadLocation(c.Query("tz", query.DefaultTimezone))
+	if err != nil {
+		errs.Add("tz", "invalid", "tz must be an IANA timezone such as Asia/Jakarta")
+	}
+	opts.Location = loc
+	if len(errs) > 0 {
+		return opts, errs
+	}
+	return opts, nil
+}
+
+// auditExport writes the export's audit entry. Exports fail closed: nothing
+// is sent if the entry cannot be saved.
+func (h *Handler) auditExport(c *fiber.Ctx, dataset string, opts export.Options, count int) error {
+	filter := string(c.Request().URI().QueryString())
+	return h.update(c, func(t domain.Repositories) error {
+		return t.InsertAuditLog(export.AuditEntry(dataset, opts, filter, count, time.Now()))
+	})
+}
+
+func streamExport(c *fiber.Ctx, name string, opts export.Options, write func(w *bufio.Writer) error) error {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-150405"), opts.Format)
+	c.Set(fiber.HeaderContentType, opts.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := write(w); err != nil {
+			log.Printf("Export %s stream error: %v", name, err)
+		}
+	})
+	return nil
+}
@@ -1,16 +1,15 @@
 package api
 
 import (
-	"audit-sendiri/internal/db"
 	"audit-sendiri/internal/domain"
-	"crypto/rand"
-	"encoding/hex"
-	"encoding/json"
+	"audit-sendiri/internal/ledger"
+	"audit-sendiri/internal/query"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
-	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
@@ -18,11 +17,12 @@ import (
 )
 
 type Handler struct {
-	DB *db.SawitDB
+	Store domain.Store
+	Keys  *ledger.KeyStore
 }
 
-func NewHandler(d *db.SawitDB) *Handler {
-	return &Handler{DB: d}
+func NewHandler(store domain.Store, keys *ledger.KeyStore) *Handler {
+	return &Handler{Store: store, Keys: keys}
 }
 
 func (h *Handler) Register(app *fiber.App) {
@@ -41,21 +41,33 @@ func (h *Handler) Register(app *fiber.App) {
 		},
 	})
 
+	app.Use(BodyLimit(DefaultBodyLimit, "/api/transactions/import"))
+
 	api.Get("/", h.GetIndex)
 	api.Post("/login", authLimiter, h.Login)
 	api.Post("/setup", authLimiter, h.Setup)
 	api.Get("/check-setup", h.CheckSetup)
 	api.Get("/transactions", h.GetTransactions)
+	api.Get("/summary", h.GetSummary)
+	api.Get("/reports/cash", h.GetCashReport)
+	api.Get("/ledger/export", h.ExportLedger)
+	api.Get("/ledger/public-key", h.GetLedgerPublicKey)
+	api.Get("/periods", h.GetPeriods)
 
 	protected := api.Use(AuthMiddleware())
 	
 	protected.Get("/audit-log", h.GetAuditLog)
+	protected.Get("/audit-log/export", h.ExportAuditLog)
+	protected.Get("/audit-log/verify", h.VerifyAuditLog)
 	protected.Get("/users", h.GetUsers)
 	protected.Get("/settings", h.GetSettings)
+	protected.Get("/categories", h.GetCategories)
+	protected.Get("/transactions/export", h.ExportTransactions)
 	
 	adminOnly := protected.Use(AdminOnly())
 	
 	adminOnly.Post("/transactions", h.CreateTransaction)
+	adminOnly.Post("/transactions/import", StreamedBodyLimit(MaxImportSize), h.ImportTransactions)
 	adminOnly.Put("/transactions/:id", h.UpdateTransaction)
 	adminOnly.Delete("/transactions/:id", h.DeleteTransaction)
 	
@@ -63,7 +75,13 @@ func (h *Handler) Register(app *fiber.App) {
 	adminOnly.Put("/users/:id", h.UpdateUser)
 	adminOnly.Delete("/users/:id", h.DeleteUser)
 
+	adminOnly.Post("/categories", h.CreateCategory)
+	adminOnly.Put("/categories/:id", h.UpdateCategory)
+	adminOnly.Delete("/categories/:id", h.DeleteCategory)
+
 	adminOnly.Put("/settings", h.UpdateSettings)
+	adminOnly.Post("/periods/close", h.ClosePeriod)
+	adminOnly.Post("/periods/:id/reopen", h.ReopenPeriod)
 	adminOnly.Post("/audit-log/:id/restore", h.RestoreAuditLog)
 }
 
@@ -72,7 +90,7 @@ func (h *Handler) GetIndex(c *fiber.Ctx) error {
 }
 
 func (h *Handler) CheckSetup(c *fiber.Ctx) error {
-	isSetup := len(h.DB.Users) > 0
+	isSetup := h.Store.UserCount() > 0
 	return c.JSON(fiber.Map{
 		"is_setup": isSetup,
 	})
@@ -85,19 +103,14 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
 	}
 
-	var foundUser *domain.User
-	for _, u := range h.DB.Users {
-		if u.Username == req.Username {
-			foundUser = &u
-			break
-		}
-	}
-
-	if foundUser == nil {
+	foundUser, found := h.Store.UserByUsername(req.Username)
+	if !found {
+		h.auditFailedLogin(c, req.Username, "", "unknown username")
 		return c.Status(401).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 
 	if !domain.CheckPasswordHash(req.Password, foundUser.PasswordHash) {
+		h.auditFailedLogin(c, req.Username, foundUser.ID, "wrong password")
 		return c.Status(401).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 	expiresAt := time.Now().Add(24 * time.Hour)
@@ -118,6 +131,21 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
 	}
 
+	err = h.update(c, func(t domain.Repositories) error {
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType:        "user",
+			EntityID:          foundUser.ID,
+			Action:            "login",
+			Note:              fmt.Sprintf("%s logged in", foundUser.Username),
+			CreatedAt:         time.Now(),
+			CreatedBy:         foundUser.ID,
+			CreatedByUsername: foundUser.Username,
+		})
+	})
+	if err != nil {
+		return persistError(c, "Login", err)
+	}
+
 	return c.JSON(domain.LoginResponse{
 		Token:     tokenString,
 		ExpiresAt: expiresAt.Unix(),
@@ -126,7 +154,7 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 }
 
 func (h *Handler) Setup(c *fiber.Ctx) error {
-	if len(h.DB.Users) > 0 {
+	if h.Store.UserCount() > 0 {
 		return c.Status(403).JSON(fiber.Map{"error": "Setup already completed"})
 	}
 
@@ -150,7 +178,7 @@ func (h *Handler) Setup(c *fiber.Ctx) error {
 	}
 
 	admin := domain.User{
-		ID:           generateID(),
+		ID:           domain.NewID(),
 		Username:     req.Username,
 		PasswordHash: hashedPassword,
 		Role:         domain.RoleAdmin,
@@ -159,14 +187,6 @@ func (h *Handler) Setup(c *fiber.Ctx) error {
 		UpdatedAt:    time.Now(),
 	}
 
-	h.DB.InsertUser(admin)
-
-	h.DB.InsertAuditLog(domain.AuditLog{
-		EntityType: "user",
-		Action:     "setup_admin",
-		CreatedAt:  time.Now(),
-	})
-
 	settings := domain.AppSettings{
 		RTName:    req.RTName,
 		RWName:    req.RWName,
@@ -174,43 +194,119 @@ func (h *Handler) Setup(c *fiber.Ctx) error {
 		Kecamatan: req.Kecamatan,
 		Address:   req.Address,
 	}
-	
-	payload, _ := json.Marshal(settings)
-	query := fmt.Sprintf("TANAM JSON settings %s", string(payload))
-	h.DB.ExecuteAQL(query)
+
+	err = h.update(c, func(t domain.Repositories) error {
+		if len(t.Users()) > 0 {
+			return &httpError{403, "Setup already completed"}
+		}
+		if err := t.InsertUser(admin); err != nil {
+			return err
+		}
+		if err := t.InsertAuditLog(domain.AuditLog{
+			EntityType:        "user",
+			EntityID:          admin.ID,
+			Action:            "setup_admin",
+			After:             domain.AuditState(admin.ToSafe()),
+			CreatedAt:         time.Now(),
+			CreatedBy:         admin.ID,
+			CreatedByUsername: admin.Username,
+		}); err != nil {
+			return err
+		}
+		if len(t.Categories()) == 0 {
+			for _, category := range domain.DefaultCategories() {
+				category.ID = domain.NewID()
+				category.CreatedAt = time.Now()
+				category.UpdatedAt = category.CreatedAt
+				if err := t.InsertCategory(category); err != nil {
+					return err
+				}
+			}
+		}
+		return t.SaveSettings(settings)
+	})
+	if err != nil {
+		return updateError(c, "Setup", err)
+	}
+
+	if _, err := h.Keys.LoadOrGenerate(); err != nil {
+		log.Printf("Setup ledger key error: %v", err)
+	}
 
 	return c.JSON(admin.ToSafe())
 }
 
 func (h *Handler) GetTransactions(c *fiber.Ctx) error {
-	var activeTransactions []domain.Transaction
-	for _, tx := range h.DB.Transactions {
-		if tx.DeletedAt == nil {
-			activeTransactions = append(activeTransactions, tx)
-		}
+	stmt, page, limit, err := query.Transactions(c.Query, h.Store.Categories(), true)
+	if err != nil {
+		return updateError(c, "GetTransactions", err)
+	}
+
+	transactions, total, err := h.Store.QueryTransactions(stmt)
+	if err != nil {
+		log.Printf("GetTransactions query error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
 	}
-	return c.JSON(activeTransactions)
+	return c.JSON(newListResponse(transactions, total, page, limit))
 }
 
 func (h *Handler) CreateTransaction(c *fiber.Ctx) error {
-	var tx domain.Transaction
-	if err := c.BodyParser(&tx); err != nil {
-		log.Printf("CreateTransaction BodyParser error: %v", err)
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
-	}
-	
-	tx.CreatedAt = time.Now()
-	if tx.ID == "" {
-		tx.ID = generateID()
+	in, date, err := parseTransactionInput(c, "CreateTransaction", false)
+	if err != nil {
+		return updateError(c, "CreateTransaction", err)
 	}
-	
-	h.DB.InsertTransaction(tx)
-	
-	h.DB.InsertAuditLog(domain.AuditLog{
-		EntityType: "transaction", 
-		Action:     "create", 
-		CreatedAt:  time.Now(),
+
+	a := actor(c)
+	now := time.Now()
+	if date == nil {
+		date = &now
+	}
+	tx := domain.Transaction{
+		ID:                domain.NewID(),
+		Type:              in.Type,
+		Amount:            *in.Amount,
+		Category:          in.Category,
+		CategoryID:        in.CategoryID,
+		Description:       in.Description,
+		TransactionDate:   date,
+		CreatedAt:         now,
+		CreatedBy:         a.UserID,
+		CreatedByUsername: a.Username,
+		AdjustmentOf:      in.AdjustmentOf,
+	}
+
+	err = h.update(c, func(t domain.Repositories) error {
+		if errs := t.Settings().CheckTransactionDate(*date, now, transactionLocation()); len(errs) > 0 {
+			return errs
+		}
+		if err := checkPeriodsOpen(t, *date); err != nil {
+			return err
+		}
+		note := fmt.Sprintf("Created transaction: %s (Amount: %s)", tx.Description, tx.Amount)
+		if tx.AdjustmentOf != "" {
+			if _, found := t.Transaction(tx.AdjustmentOf); !found {
+				return domain.ValidationErrors{{Field: "adjustment_of", Code: "not_found", Message: "adjusted transaction not found"}}
+			}
+			note = fmt.Sprintf("Created adjusting entry for %s: %s (Amount: %s)", tx.AdjustmentOf, tx.Description, tx.Amount)
+		}
+		if err := applyCategory(t, &tx); err != nil {
+			return err
+		}
+		if err := t.InsertTransaction(tx); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "transaction",
+			EntityID:   tx.ID,
+			Action:     "create",
+			Note:       note,
+			After:      domain.AuditState(tx),
+			CreatedAt:  time.Now(),
+		})
 	})
+	if err != nil {
+		return updateError(c, "CreateTransaction", err)
+	}
 
 	return c.JSON(tx)
 }
@@ -221,63 +317,98 @@ func (h *Handler) UpdateTransaction(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "ID required"})
 	}
 
-	var req domain.Transaction
-	if err := c.BodyParser(&req); err != nil {
-		log.Printf("UpdateTransaction BodyParser error: %v", err)
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
+	req, date, err := parseTransactionInput(c, "UpdateTransaction", true)
+	if err != nil {
+		return updateError(c, "UpdateTransaction", err)
 	}
 
 	var existingTx domain.Transaction
-	var found bool
-	for _, tx := range h.DB.Transactions {
-		if tx.ID == id {
-			existingTx = tx
-			found = true
-			break
+	err = h.update(c, func(t domain.Repositories) error {
+		var found bool
+		existingTx, found = t.Transaction(id)
+		if !found {
+			return &httpError{404, "Transaction not found"}
+		}
+		// An immutable ledger leaves the transaction, and so its period,
+		// as it is.
+		immutable := t.Settings().ImmutableLedger
+		if immutable {
+			if err := checkCorrectable(t, existingTx); err != nil {
+				return err
+			}
+		} else if err := checkPeriodsOpen(t, existingTx.Date()); err != nil {
+			return err
 		}
-	}
-
-	if !found {
-		return c.Status(404).JSON(fiber.Map{"error": "Transaction not found"})
-	}
-
-	changes := make(map[string]string)
-	if req.Amount != 0 && req.Amount != existingTx.Amount {
-		changes["amount"] = fmt.Sprintf("%.2f -> %.2f", existingTx.Amount, req.Amount)
-		existingTx.Amount = req.Amount
-	}
-	if req.Type != "" && req.Type != existingTx.Type {
-		changes["type"] = fmt.Sprintf("%s -> %s", existingTx.Type, req.Type)
-		existingTx.Type = req.Type
-	}
-	if req.Category != "" && req.Category != existingTx.Category {
-		changes["category"] = fmt.Sprintf("%s -> %s", existingTx.Category, req.Category)
-		existingTx.Category = req.Category
-	}
-	if req.Description != "" && req.Description != existingTx.Description {
-		changes["description"] = fmt.Sprintf("%s -> %s", existingTx.Description, req.Description)
-		existingTx.Description = req.Description
-	}
-
-	if len(changes) > 0 {
-		h.DB.UpdateTransaction(existingTx)
 
-		detailsJSON, _ := json.Marshal(changes)
+		previous := existingTx
+		var changes []string
+		if req.Amount != nil && *req.Amount != existingTx.Amount {
+			changes = append(changes, fmt.Sprintf("amount: %s -> %s", existingTx.Amount, *req.Amount))
+			existingTx.Amount = *req.Amount
+		}
+		if req.Type != "" && req.Type != existingTx.Type {
+			changes = append(changes, fmt.Sprintf("type: %s -> %s", existingTx.Type, req.Type))
+			existingTx.Type = req.Type
+		}
+		if req.CategoryID != "" || req.Category != "" {
+			existingTx.CategoryID = req.CategoryID
+			existingTx.Category = req.Category
+		}
+		if existingTx.Type != previous.Type || req.CategoryID != "" || req.Category != "" {
+			if err := applyCategory(t, &existingTx); err != nil {
+				return err
+			}
+		}
+		if existingTx.Category != previous.Category {
+			changes = append(changes, fmt.Sprintf("category: %s -> %s", previous.Category, existingTx.Category))
+		}
+		if req.Description != "" && req.Description != existingTx.Description {
+			changes = append(changes, fmt.Sprintf("description: %s -> %s", existingTx.Description, req.Description))
+			existingTx.Description = req.Description
+		}
+		dateChanged := date != nil && !sameTransactionDate(existingTx.Date(), *date, req.TransactionDate)
+		if dateChanged {
+			if errs := t.Settings().CheckTransactionDate(*date, time.Now(), transactionLocation()); len(errs) > 0 {
+				return errs
+			}
+			if err := checkPeriodsOpen(t, *date); err != nil {
+				return err
+			}
+			loc := transactionLocation()
+			changes = append(changes, fmt.Sprintf("transaction_date: %s -> %s",
+				existingTx.Date().In(loc).Format("2006-01-02"), date.In(loc).Format("2006-01-02")))
+			existingTx.TransactionDate = date
+		}
 
-		var noteParts []string
-		for k, v := range changes {
-			noteParts = append(noteParts, fmt.Sprintf("%s: %s", k, v))
+		if len(changes) == 0 {
+			return nil
 		}
-		note := strings.Join(noteParts, "; ")
 
-		h.DB.InsertAuditLog(domain.AuditLog{
+		if immutable {
+			now := time.Now()
+			if !dateChanged {
+				existingTx.TransactionDate = &now
+			}
+			var err error
+			existingTx, err = postCorrection(t, previous, existingTx, changes, actor(c), now)
+			return err
+		}
+		existingTx.Touch(actor(c), time.Now())
+		if err := t.UpdateTransaction(existingTx); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
 			EntityType: "transaction",
 			EntityID:   existingTx.ID,
 			Action:     "update",
-			Note:       note,
-			Details:    string(detailsJSON),
+			Note:       strings.Join(changes, "; "),
+			Before:     domain.AuditState(previous),
+			After:      domain.AuditState(existingTx),
 			CreatedAt:  time.Now(),
 		})
+	})
+	if err != nil {
+		return updateError(c, "UpdateTransaction", err)
 	}
 
 	return c.JSON(existingTx)
@@ -289,49 +420,66 @@ func (h *Handler) DeleteTransaction(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "ID required"})
 	}
 
-	var existingTx domain.Transaction
-	var found bool
-	for _, tx := range h.DB.Transactions {
-		if tx.ID == id {
-			existingTx = tx
-			found = true
-			break
+	err := h.update(c, func(t domain.Repositories) error {
+		existingTx, found := t.Transaction(id)
+		if !found {
+			return &httpError{404, "Transaction not found"}
+		}
+		if t.Settings().ImmutableLedger {
+			if err := checkCorrectable(t, existingTx); err != nil {
+				return err
+			}
+			note := fmt.Sprintf("Reversed transaction %s instead of deleting: %s (Amount: %s)", existingTx.ID, existingTx.Description, existingTx.Amount)
+			_, err := postReversal(t, existingTx, note, actor(c), time.Now())
+			return err
+		}
+		if err := checkPeriodsOpen(t, existingTx.Date()); err != nil {
+			return err
 		}
-	}
-
-	if !found {
-		return c.Status(404).JSON(fiber.Map{"error": "Transaction not found"})
-	}
 
-	now := time.Now()
-	existingTx.DeletedAt = &now
-	h.DB.UpdateTransaction(existingTx)
-	note := fmt.Sprintf("Deleted transaction: %s (Amount: %.2f)", existingTx.Description, existingTx.Amount)
-	h.DB.InsertAuditLog(domain.AuditLog{
-		EntityType: "transaction",
-		EntityID:   existingTx.ID,
-		Action:     "delete",
-		Note:       note,
-		CreatedAt:  time.Now(),
+		previous := existingTx
+		now := time.Now()
+		existingTx.DeletedAt = &now
+		existingTx.Touch(actor(c), now)
+		note := fmt.Sprintf("Deleted transaction: %s (Amount: %s)", existingTx.Description, existingTx.Amount)
+		if err := t.UpdateTransaction(existingTx); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "transaction",
+			EntityID:   existingTx.ID,
+			Action:     "delete",
+			Note:       note,
+			Before:     domain.AuditState(previous),
+			After:      domain.AuditState(existingTx),
+			CreatedAt:  time.Now(),
+		})
 	})
+	if err != nil {
+		return updateError(c, "DeleteTransaction", err)
+	}
 
 	return c.SendStatus(200)
 }
 
 func (h *Handler) GetAuditLog(c *fiber.Ctx) error {
-	return c.JSON(h.DB.AuditLogs)
+	return c.JSON(auditLogViews(h.Store.AuditLogs(), h.Store.Users()))
+}
+
+func (h *Handler) VerifyAuditLog(c *fiber.Ctx) error {
+	return c.JSON(domain.VerifyAuditChain(h.Store.AuditLogs()))
 }
 
 func (h *Handler) GetUsers(c *fiber.Ctx) error {
 	var safeUsers []domain.SafeUser
-	for _, u := range h.DB.Users {
+	for _, u := range h.Store.Users() {
 		safeUsers = append(safeUsers, u.ToSafe())
 	}
 	return c.JSON(safeUsers)
 }
 
 func (h *Handler) GetSettings(c *fiber.Ctx) error {
-	return c.JSON(h.DB.Settings)
+	return c.JSON(h.Store.Settings())
 }
 
 func (h *Handler) UpdateSettings(c *fiber.Ctx) error {
@@ -340,11 +488,31 @@ func (h *Handler) UpdateSettings(c *fiber.Ctx) error {
 		log.Printf("UpdateSettings BodyParser error: %v", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
 	}
-	
-	payload, _ := json.Marshal(settings)
-	query := fmt.Sprintf("TANAM JSON settings %s", string(payload))
-	h.DB.ExecuteAQL(query)
-	
+	if errs := settings.Validate(); len(errs) > 0 {
+		return updateError(c, "UpdateSettings", errs)
+	}
+
+	err := h.update(c, func(t domain.Repositories) error {
+		before := t.Settings()
+		if before == settings {
+			return nil
+		}
+		if err := t.SaveSettings(settings); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "settings",
+			Action:     "update",
+			Note:       "Updated settings",
+			Before:     domain.AuditState(before),
+			After:      domain.AuditState(settings),
+			CreatedAt:  time.Now(),
+		})
+	})
+	if err != nil {
+		return updateError(c, "UpdateSettings", err)
+	}
+
 	return c.JSON(settings)
 }
 
@@ -369,7 +537,7 @@ func (h *Handler) CreateUser(c *fiber.Ctx) error {
 	}
 
 	user := domain.User{
-		ID:           generateID(),
+		ID:           domain.NewID(),
 		Username:     req.Username,
 		PasswordHash: hashedPassword,
 		FullName:     req.FullName,
@@ -378,8 +546,23 @@ func (h *Handler) CreateUser(c *fiber.Ctx) error {
 		UpdatedAt:    time.Now(),
 	}
 
-	h.DB.InsertUser(user)
-	
+	err = h.update(c, func(t domain.Repositories) error {
+		if err := t.InsertUser(user); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "user",
+			EntityID:   user.ID,
+			Action:     "create",
+			Note:       fmt.Sprintf("Created user %s with role %s", user.Username, user.Role),
+			After:      domain.AuditState(user.ToSafe()),
+			CreatedAt:  time.Now(),
+		})
+	})
+	if err != nil {
+		return updateError(c, "CreateUser", err)
+	}
+
 	return c.JSON(user.ToSafe())
 }
 
@@ -406,40 +589,64 @@ func (h *Handler) UpdateUser(c *fiber.Ctx) error {
 		}
 	}
 
-	var existingUser domain.User
-	var found bool
-	for _, u := range h.DB.Users {
-		if u.ID == id {
-			existingUser = u
-			found = true
-			break
-		}
-	}
-	if !found {
-		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
-	}
-
-	if req.Username != "" {
-		existingUser.Username = req.Username
-	}
+	var hashedPassword string
 	if req.Password != "" {
-		hashedPassword, err := domain.HashPassword(req.Password)
+		var err error
+		hashedPassword, err = domain.HashPassword(req.Password)
 		if err != nil {
 			log.Printf("Password hashing error: %v", err)
 			return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
 		}
-		existingUser.PasswordHash = hashedPassword
 	}
-	if req.FullName != "" {
-		existingUser.FullName = req.FullName
-	}
-	if req.Role != "" {
-		existingUser.Role = req.Role
+
+	var existingUser domain.User
+	err := h.update(c, func(t domain.Repositories) error {
+		var found bool
+		existingUser, found = t.User(id)
+		if !found {
+			return &httpError{404, "User not found"}
+		}
+
+		before := existingUser
+		var changes []string
+		if req.Username != "" && req.Username != existingUser.Username {
+			changes = append(changes, fmt.Sprintf("username: %s -> %s", existingUser.Username, req.Username))
+			existingUser.Username = req.Username
+		}
+		if hashedPassword != "" {
+			changes = append(changes, "password reset")
+			existingUser.PasswordHash = hashedPassword
+		}
+		if req.FullName != "" && req.FullName != existingUser.FullName {
+			changes = append(changes, fmt.Sprintf("full name: %s -> %s", existingUser.FullName, req.FullName))
+			existingUser.FullName = req.FullName
+		}
+		if req.Role != "" && req.Role != existingUser.Role {
+			changes = append(changes, fmt.Sprintf("role: %s -> %s", existingUser.Role, req.Role))
+			existingUser.Role = req.Role
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		existingUser.UpdatedAt = time.Now()
+
+		if err := t.UpdateUser(existingUser); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "user",
+			EntityID:   existingUser.ID,
+			Action:     "update",
+			Note:       fmt.Sprintf("Updated user %s: %s", before.Username, strings.Join(changes, "; ")),
+			Before:     domain.AuditState(userAuditState(before, false)),
+			After:      domain.AuditState(userAuditState(existingUser, hashedPassword != "")),
+			CreatedAt:  time.Now(),
+		})
+	})
+	if err != nil {
+		return updateError(c, "UpdateUser", err)
 	}
-	existingUser.UpdatedAt = time.Now()
 
-	h.DB.UpdateUser(existingUser)
-	
 	return c.JSON(existingUser.ToSafe())
 }
 
@@ -453,118 +660,185 @@ func (h *Handler) DeleteUser(c *fiber.Ctx) error {
 	if ok && userID == id {
 		return c.Status(400).JSON(fiber.Map{"error": "Cannot delete your own account"})
 	}
-	adminCount := 0
-	var targetUser *domain.User
-	for _, u := range h.DB.Users {
-		if u.Role == domain.RoleAdmin {
-			adminCount++
+
+	err := h.update(c, func(t domain.Repositories) error {
+		adminCount := 0
+		var targetUser *domain.User
+		for _, u := range t.Users() {
+			if u.Role == domain.RoleAdmin {
+				adminCount++
+			}
+			if u.ID == id {
+				copyUser := u
+				targetUser = &copyUser
+			}
+		}
+
+		if targetUser != nil && targetUser.Role == domain.RoleAdmin && adminCount <= 1 {
+			return &httpError{400, "Cannot delete the last admin user"}
 		}
-		if u.ID == id {
-			copyUser := u
-			targetUser = &copyUser
+		if targetUser == nil {
+			return &httpError{404, "User not found"}
 		}
-	}
 
-	if targetUser != nil && targetUser.Role == domain.RoleAdmin && adminCount <= 1 {
-		return c.Status(400).JSON(fiber.Map{"error": "Cannot delete the last admin user"})
-	}
-	if targetUser == nil {
-		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+		if err := t.DeleteUser(id); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "user",
+			EntityID:   id,
+			Action:     "delete",
+			Note:       fmt.Sprintf("Deleted user %s", targetUser.Username),
+			Before:     domain.AuditState(targetUser.ToSafe()),
+			CreatedAt:  time.Now(),
+		})
+	})
+	if err != nil {
+		return updateError(c, "DeleteUser", err)
 	}
-
-	h.DB.DeleteUser(id)
 	return c.SendStatus(200)
 }
 
 func (h *Handler) RestoreAuditLog(c *fiber.Ctx) error {
 	id := c.Params("id")
-	
-	var logEntry *domain.AuditLog
-	for _, l := range h.DB.AuditLogs {
-		if l.ID == id {
-			entry := l
-			logEntry = &entry
-			break
-		}
-	}
-	
-	if logEntry == nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Audit log not found"})
-	}
-
-	if logEntry.EntityType != "transaction" {
-		 return c.Status(400).JSON(fiber.Map{"error": "Only transaction restoration is supported"})
-	}
 
-	var targetTx *domain.Transaction
-	for i := range h.DB.Transactions {
-		if h.DB.Transactions[i].ID == logEntry.EntityID {
-			targetTx = &h.DB.Transactions[i]
-			break
+	var targetTx domain.Transaction
+	err := h.update(c, func(t domain.Repositories) error {
+		logEntry, found := t.AuditLog(id)
+		if !found {
+			return &httpError{404, "Audit log not found"}
 		}
-	}
 
-	if targetTx == nil {
-		 return c.Status(404).JSON(fiber.Map{"error": "Transaction not found"})
-	}
+		if logEntry.EntityType != "transaction" {
+			return &httpError{400, "Only transaction restoration is supported"}
+		}
+		// Corrections stay in the ledger even after immutable-ledger mode is
+		// turned off; they are undone by correcting again, never reverted.
+		if logEntry.Action == "correction" {
+			return &httpError{409, "Corrections cannot be restored; correct the transaction again instead"}
+		}
+		if t.Settings().ImmutableLedger {
+			return &httpError{409, "Restoring is disabled in immutable-ledger mode; edit the transaction to post a correction"}
+		}
 
-	if logEntry.Action == "delete" {
-		targetTx.DeletedAt = nil
-		h.DB.UpdateTransaction(*targetTx)
-		
-		h.DB.InsertAuditLog(domain.AuditLog{
-			EntityType: "transaction",
-			EntityID:   targetTx.ID,
-			Action:     "create",
-			Note:       fmt.Sprintf("Restored from deletion (Audit Log ID: %s)", logEntry.ID),
-			CreatedAt:  time.Now(),
-		})
-	} else if logEntry.Action == "update" || logEntry.Action == "correction" {
-		var changes map[string]string
-		if err := json.Unmarshal([]byte(logEntry.Details), &changes); err != nil {
-			 return c.Status(500).JSON(fiber.Map{"error": "Failed to parse audit details"})
+		targetTx, found = t.Transaction(logEntry.EntityID)
+		if !found {
+			return &httpError{404, "Transaction not found"}
+		}
+		if err := checkPeriodsOpen(t, targetTx.Date()); err != nil {
+			return err
 		}
 
-		for field, change := range changes {
-			parts := strings.Split(change, " -> ")
-			if len(parts) < 1 {
-				continue
+		previous := targetTx
+		if logEntry.Action == "delete" {
+			targetTx.DeletedAt = nil
+			targetTx.Touch(actor(c), time.Now())
+			if err := t.UpdateTransaction(targetTx); err != nil {
+				return err
 			}
-			oldValueStr := parts[0]
-			
-			switch field {
-			case "amount":
-				if val, err := strconv.ParseFloat(oldValueStr, 64); err == nil {
-					targetTx.Amount = val
-				}
-			case "type":
-				targetTx.Type = oldValueStr
-			case "category":
-				targetTx.Category = oldValueStr
-			case "description":
-				targetTx.Description = oldValueStr
+			return t.InsertAuditLog(domain.AuditLog{
+				EntityType: "transaction",
+				EntityID:   targetTx.ID,
+				Action:     "create",
+				Note:       fmt.Sprintf("Restored from deletion (Audit Log ID: %s)", logEntry.ID),
+				Before:     domain.AuditState(previous),
+				After:      domain.AuditState(targetTx),
+				CreatedAt:  time.Now(),
+			})
+		} else if logEntry.Action == "update" {
+			changes, err := logEntry.Changes()
+			if err != nil {
+				return &httpError{409, fmt.Sprintf("Audit entry cannot be restored: %v", err)}
+			}
+			targetTx, err = targetTx.Revert(changes)
+			if err != nil {
+				return &httpError{409, fmt.Sprintf("Audit entry cannot be restored: %v", err)}
+			}
+			if err := checkPeriodsOpen(t, targetTx.Date()); err != nil {
+				return err
 			}
+			if err := applyCategory(t, &targetTx); err != nil {
+				return err
+			}
+			targetTx.Touch(actor(c), time.Now())
+			if err := t.UpdateTransaction(targetTx); err != nil {
+				return err
+			}
+			return t.InsertAuditLog(domain.AuditLog{
+				EntityType: "transaction",
+				EntityID:   targetTx.ID,
+				Action:     "update",
+				Note:       fmt.Sprintf("Restored from update (Audit Log ID: %s)", logEntry.ID),
+				Before:     domain.AuditState(previous),
+				After:      domain.AuditState(targetTx),
+				CreatedAt:  time.Now(),
+			})
 		}
-		h.DB.UpdateTransaction(*targetTx)
+		return &httpError{400, "Action not restorable"}
+	})
+	if err != nil {
+		return updateError(c, "RestoreAuditLog", err)
+	}
 
-		h.DB.InsertAuditLog(domain.AuditLog{
-			EntityType: "transaction",
-			EntityID:   targetTx.ID,
-			Action:     "update",
-			Note:       fmt.Sprintf("Restored from update (Audit Log ID: %s)", logEntry.ID),
+	return c.JSON(targetTx)
+}
+
+// auditFailedLogin records a rejected login. Failing to write the entry
+// does not change the response.
+func (h *Handler) auditFailedLogin(c *fiber.Ctx, username, userID, reason string) {
+	if utf8.RuneCountInString(username) > 64 {
+		username = string([]rune(username)[:64])
+	}
+	err := h.update(c, func(t domain.Repositories) error {
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "user",
+			EntityID:   userID,
+			Action:     "login_failed",
+			Note:       fmt.Sprintf("Failed login for %q: %s", username, reason),
+			After:      domain.AuditState(map[string]string{"username": username}),
 			CreatedAt:  time.Now(),
 		})
-	} else {
-		return c.Status(400).JSON(fiber.Map{"error": "Action not restorable"})
+	})
+	if err != nil {
+		log.Printf("Login audit error: %v", err)
 	}
+}
 
-	return c.JSON(targetTx)
+// userAuditState is the audited view of a user: never the password hash,
+// only whether the password was changed.
+func userAuditState(u domain.User, passwordChanged bool) interface{} {
+	return struct {
+		domain.SafeUser
+		PasswordChanged bool `json:"password_changed,omitempty"`
+	}{u.ToSafe(), passwordChanged}
 }
 
-func generateID() string {
-	bytes := make([]byte, 8)
-	if _, err := rand.Read(bytes); err != nil {
-		return fmt.Sprintf("%d", time.Now().UnixNano())
+// httpError aborts an Update with a client-facing status instead of a
+// persistence failure.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func updateError(c *fiber.Ctx, handler string, err error) error {
+	var he *httpError
+	if errors.As(err, &he) {
+		return c.Status(he.status).JSON(fiber.Map{"error": he.message})
 	}
-	return hex.EncodeToString(bytes)
+	var ve domain.ValidationErrors
+	if errors.As(err, &ve) {
+		return c.Status(400).JSON(fiber.Map{"error": "Validation failed", "errors": ve})
+	}
+	return persistError(c, handler, err)
+}
+
+// persistError reports a failed durable write. Nothing was applied in memory,
+// so the client must not be told the change succeeded.
+func persistError(c *fiber.Ctx, handler string, err error) error {
+	log.Printf("%s persistence error: %v", handler, err)
+	return c.Status(500).JSON(fiber.Map{"error": "Failed to save changes, please try again"})
 }
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"audit-sendiri/internal/db"
+	"audit-sendiri/internal/domain"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRestoreAuditLog(t *testing.T) {
+	date := time.Now().Add(-time.Hour)
+	category := domain.Category{ID: "c1", Kind: "expense", Code: "KEBERSIHAN", Name: "Kebersihan", Active: true}
+	original := domain.Transaction{ID: "t1", Type: "expense", Amount: domain.Rupiah(50000), Category: category.Name, CategoryID: category.ID, CreatedAt: date}
+	edited := original
+	edited.Amount = domain.Rupiah(75000)
+	reversal := original.Reverse("t2", domain.Actor{UserID: "u1"}, date)
+
+	tests := []struct {
+		name      string
+		immutable bool
+		entry     domain.AuditLog
+		wantCode  int
+	}{
+		{
+			name:     "update",
+			entry:    domain.AuditLog{ID: "a1", EntityType: "transaction", EntityID: "t1", Action: "update", Before: domain.AuditState(original), After: domain.AuditState(edited)},
+			wantCode: 200,
+		},
+		{
+			name:      "update in immutable-ledger mode",
+			immutable: true,
+			entry:     domain.AuditLog{ID: "a1", EntityType: "transaction", EntityID: "t1", Action: "update", Before: domain.AuditState(original), After: domain.AuditState(edited)},
+			wantCode:  409,
+		},
+		{
+			// Posted while the mode was on, restored after it was turned off.
+			name:     "correction",
+			entry:    domain.AuditLog{ID: "a1", EntityType: "transaction", EntityID: "t2", Action: "correction", After: domain.AuditState(reversal)},
+			wantCode: 409,
+		},
+		{
+			name:     "category",
+			entry:    domain.AuditLog{ID: "a1", EntityType: "category", EntityID: "c1", Action: "update"},
+			wantCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		store := db.NewMemoryStore()
+		if err := store.InsertCategory(category); err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range []domain.Transaction{edited, reversal} {
+			if err := store.InsertTransaction(tx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := store.InsertAuditLog(tt.entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveSettings(domain.AppSettings{ImmutableLedger: tt.immutable}); err != nil {
+			t.Fatal(err)
+		}
+
+		app := fiber.New()
+		app.Post("/audit-log/:id/restore", NewHandler(store, nil).RestoreAuditLog)
+		resp, err := app.Test(httptest.NewRequest("POST", "/audit-log/a1/restore", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.wantCode)
+		}
+
+		restored, _ := store.Transaction("t1")
+		wantAmount := edited.Amount
+		if tt.wantCode == 200 {
+			wantAmount = original.Amount
+		}
+		if restored.Amount != wantAmount {
+			t.Errorf("%s: amount %s, want %s", tt.name, restored.Amount, wantAmount)
+		}
+		if r, _ := store.Transaction("t2"); r.DeletedAt != nil || r.Amount != reversal.Amount {
+			t.Errorf("%s: reversing entry changed: %+v", tt.name, r)
+		}
+	}
+}
+
+func TestFailedLoginTruncatesUsername(t *testing.T) {
+	store := db.NewMemoryStore()
+	app := fiber.New()
+	app.Post("/login", NewHandler(store, nil).Login)
+
+	body, _ := json.Marshal(map[string]string{"username": strings.Repeat("é", 100), "password": "x"})
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 401 {
+		t.Fatalf("status %d, want 401", resp.StatusCode)
+	}
+
+	logs := store.AuditLogs()
+	if len(logs) != 1 {
+		t.Fatalf("%d audit entries, want 1", len(logs))
+	}
+	var after map[string]string
+	if err := json.Unmarshal(logs[0].After, &after); err != nil {
+		t.Fatal(err)
+	}
+	if u := after["username"]; u != strings.Repeat("é", 64) || !utf8.ValidString(logs[0].Note) {
+		t.Errorf("username = %q (%d runes), note valid UTF-8: %v", u, utf8.RuneCountInString(u), utf8.ValidString(logs[0].Note))
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/importer"
+	"audit-sendiri/internal/query"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportTransactions previews a CSV or XLSX cash book upload, and with
+// commit=true writes it in one batch. The file goes in the "file" form
+// field; date, type, amount, income, expense, category and description map
+// columns by header or 1-based number, and date_format, delimiter, tz and
+// create_categories tune parsing.
+func (h *Handler) ImportTransactions(c *fiber.Ctx) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	format, err := importer.FormatOf(fh.Filename)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	f, err := fh.Open()
+	if err != nil {
+		log.Printf("ImportTransactions open error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		log.Printf("ImportTransactions read error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+
+	var errs domain.ValidationErrors
+	opts := importer.Options{
+		Mapping: importer.Mapping{
+			Date:        c.FormValue("date"),
+			Type:        c.FormValue("type"),
+			Amount:      c.FormValue("amount"),
+			Income:      c.FormValue("income"),
+			Expense:     c.FormValue("expense"),
+			Category:    c.FormValue("category"),
+			Description: c.FormValue("description"),
+		},
+		DateFormat:       c.FormValue("date_format"),
+		CreateCategories: c.FormValue("create_categories") == "true",
+		ClosedPeriods:    h.Store.ClosedPeriods(),
+	}
+	opts.Location, err = time.LoadLocation(c.FormValue("tz", query.DefaultTimezone))
+	if err != nil {
+		errs.Add("tz", "invalid", "tz must be an IANA timezone such as Asia/Jakarta")
+	}
+	var delimiter rune
+	switch d := c.FormValue("delimiter"); strings.ToLower(d) {
+	case "":
+	case ",", ";", "|":
+		delimiter = rune(d[0])
+	case "tab", "\t":
+		delimiter = '\t'
+	default:
+		errs.Add("delimiter", "invalid", "delimiter must be one of , ; | or tab")
+	}
+	if len(errs) > 0 {
+		return updateError(c, "ImportTransactions", errs)
+	}
+
+	rows, err := importer.ReadRows(data, format, delimiter)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	job, err := importer.Parse(rows, fh.Filename, format, h.Store.Categories(), opts)
+	if err != nil {
+		var verrs domain.ValidationErrors
+		if errors.As(err, &verrs) {
+			return updateError(c, "ImportTransactions", verrs)
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if c.FormValue("commit") != "true" {
+		return c.JSON(job)
+	}
+	if job.InvalidRows > 0 || job.ValidRows == 0 {
+		msg := importer.ErrInvalidRows.Error()
+		if job.InvalidRows == 0 {
+			msg = "File has no transactions to import"
+		}
+		return c.Status(400).JSON(fiber.Map{"error": msg, "import": job})
+	}
+
+	// The file is parsed again against the categories and closed periods
+	// as they are inside the update, which may differ from the preview.
+	a := actor(c)
+	err = h.update(c, func(t domain.Repositories) error {
+		opts.ClosedPeriods = t.ClosedPeriods()
+		var err error
+		if job, err = importer.Parse(rows, fh.Filename, format, t.Categories(), opts); err != nil {
+			return err
+		}
+		return job.Commit(t, a, time.Now())
+	})
+	if errors.Is(err, importer.ErrInvalidRows) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error(), "import": job})
+	}
+	if err != nil {
+		return updateError(c, "ImportTransactions", err)
+	}
+	return c.JSON(job)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/ledger"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportLedger serves the active transactions, balance and audit chain head
+// signed with the instance key, so residents can check the file offline
+// with cmd/verify-ledger.
+func (h *Handler) ExportLedger(c *fiber.Ctx) error {
+	if h.Store.UserCount() == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Setup has not been completed"})
+	}
+
+	key, err := h.Keys.LoadOrGenerate()
+	if err != nil {
+		log.Printf("ExportLedger key error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+
+	now := time.Now()
+	var l ledger.Ledger
+	h.Store.View(func(s domain.Snapshot) error {
+		l = ledger.Build(s.Settings(), s.Transactions(), s.AuditLogs(), now)
+		return nil
+	})
+	signed, err := ledger.Sign(l, key)
+	if err != nil {
+		log.Printf("ExportLedger signing error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+
+	body, err := ledger.Encode(signed)
+	if err != nil {
+		log.Printf("ExportLedger encoding error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+
+	filename := fmt.Sprintf("ledger-RT%s-RW%s-%s.json", l.Issuer.RTName, l.Issuer.RWName, now.Format("20060102"))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(body)
+}
+
+func (h *Handler) GetLedgerPublicKey(c *fiber.Ctx) error {
+	if h.Store.UserCount() == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Setup has not been completed"})
+	}
+
+	key, err := h.Keys.LoadOrGenerate()
+	if err != nil {
+		log.Printf("GetLedgerPublicKey key error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+
+	pub := key.Public().(ed25519.PublicKey)
+	return c.JSON(fiber.Map{
+		"algorithm":  ledger.Algorithm,
+		"public_key": base64.StdEncoding.EncodeToString(pub),
+		"key_id":     ledger.KeyID(pub),
+	})
+}
@@ -0,0 +1,23 @@
+package api
+
+// listResponse is the envelope for paginated listings.
+type listResponse[T any] struct {
+	Data  []T `json:"data"`
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Pages int `json:"pages"`
+}
+
+func newListResponse[T any](data []T, total, page, limit int) listResponse[T] {
+	if data == nil {
+		data = []T{}
+	}
+	return listResponse[T]{
+		Data:  data,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+		Pages: (total + limit - 1) / limit,
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"audit-sendiri/internal/domain"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -90,3 +91,73 @@ func AdminOnly() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// Request body limits. The server runs with DefaultBodyLimit and streams
+// request bodies, so a body is only read once a route's limit has been
+// checked; imports alone may be as large as MaxImportSize.
+const (
+	DefaultBodyLimit = 4 * 1024 * 1024
+	MaxImportSize    = 32 * 1024 * 1024
+)
+
+// BodyLimit rejects request bodies over limit, except on the exempt paths,
+// and otherwise reads the body so handlers see it as usual.
+func BodyLimit(limit int, exempt ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := strings.TrimRight(c.Path(), "/")
+		for _, p := range exempt {
+			// Routing ignores case and a trailing slash, and so must this.
+			if strings.EqualFold(path, p) {
+				return c.Next()
+			}
+		}
+
+		req := c.Request()
+		if req.Header.ContentLength() > limit {
+			return bodyTooLarge(c)
+		}
+		if req.IsBodyStream() {
+			// Chunked bodies declare no length; read one byte past the limit
+			// to find out.
+			body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+			}
+			if len(body) > limit {
+				return bodyTooLarge(c)
+			}
+			req.SetBody(body)
+		} else if len(req.Body()) > limit {
+			return bodyTooLarge(c)
+		}
+		return c.Next()
+	}
+}
+
+// StreamedBodyLimit is BodyLimit for routes that read their body as a
+// stream, such as file uploads. The body is left unread, so the request
+// must declare its length.
+func StreamedBodyLimit(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		n := c.Request().Header.ContentLength()
+		if n < 0 {
+			c.Context().SetConnectionClose()
+			return c.Status(411).JSON(fiber.Map{
+				"error": "Content-Length required",
+			})
+		}
+		if n > limit {
+			return bodyTooLarge(c)
+		}
+		return c.Next()
+	}
+}
+
+// bodyTooLarge responds 413 and closes the connection, since the rest of
+// the body is never read.
+func bodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return c.Status(413).JSON(fiber.Map{
+		"error": "Request body too large",
+	})
+}
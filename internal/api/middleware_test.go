@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBodyLimit(t *testing.T) {
+	// Configured like the server, with small limits.
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    1024,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use(BodyLimit(1024, "/api/import"))
+	app.Post("/api/login", func(c *fiber.Ctx) error {
+		return c.SendString(strconv.Itoa(len(c.Body())))
+	})
+	app.Post("/api/import", StreamedBodyLimit(64*1024), func(c *fiber.Ctx) error {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(400).SendString(err.Error())
+		}
+		return c.SendString(strconv.FormatInt(fh.Size, 10))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	upload := func(size int) (*bytes.Buffer, string) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, _ := w.CreateFormFile("file", "kas.csv")
+		part.Write(bytes.Repeat([]byte("x"), size))
+		w.Close()
+		return &body, w.FormDataContentType()
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		size     int
+		chunked  bool
+		multi    bool
+		wantCode int
+		wantBody string
+	}{
+		{name: "small", path: "/api/login", size: 512, wantCode: 200, wantBody: "512"},
+		{name: "over the default limit", path: "/api/login", size: 2048, wantCode: 413},
+		{name: "chunked small", path: "/api/login", size: 512, chunked: true, wantCode: 200, wantBody: "512"},
+		{name: "chunked over the limit", path: "/api/login", size: 2048, chunked: true, wantCode: 413},
+		{name: "import", path: "/api/import", size: 20 * 1024, multi: true, wantCode: 200, wantBody: "20480"},
+		{name: "import with trailing slash", path: "/api/import/", size: 20 * 1024, multi: true, wantCode: 200, wantBody: "20480"},
+		{name: "import in other case", path: "/API/Import", size: 20 * 1024, multi: true, wantCode: 200, wantBody: "20480"},
+		{name: "import over its limit", path: "/api/import", size: 100 * 1024, multi: true, wantCode: 413},
+		{name: "chunked import", path: "/api/import", size: 512, multi: true, chunked: true, wantCode: 411},
+	}
+	for _, tt := range tests {
+		var body io.Reader = bytes.NewReader(bytes.Repeat([]byte("a"), tt.size))
+		contentType := "text/plain"
+		if tt.multi {
+			body, contentType = upload(tt.size)
+		}
+		if tt.chunked {
+			// Hide the length so the request is sent chunked.
+			body = io.MultiReader(body)
+		}
+		req, err := http.NewRequest("POST", "http://"+ln.Addr().String()+tt.path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantCode || (tt.wantBody != "" && string(got) != tt.wantBody) {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, resp.StatusCode, got, tt.wantCode, tt.wantBody)
+		}
+		if resp.StatusCode == http.StatusRequestEntityTooLarge && !resp.Close {
+			t.Errorf("%s: connection kept open after an unread body", tt.name)
+		}
+	}
+}
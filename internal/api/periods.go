@@ -0,0 +1,160 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/report"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handler) GetPeriods(c *fiber.Ctx) error {
+	periods := h.Store.ClosedPeriods()
+	if periods == nil {
+		periods = []domain.ClosedPeriod{}
+	}
+	return c.JSON(periods)
+}
+
+type closePeriodRequest struct {
+	Year  int    `json:"year"`
+	Month int    `json:"month"`
+	Note  string `json:"note"`
+}
+
+// ClosePeriod does tutup buku for a finished month: it records the month's
+// totals and closing balance, and from then on transactions dated in it
+// cannot be created, changed, deleted or restored until it is reopened.
+func (h *Handler) ClosePeriod(c *fiber.Ctx) error {
+	var req closePeriodRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("ClosePeriod BodyParser error: %v", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	var errs domain.ValidationErrors
+	if req.Year < 1900 || req.Year > 9999 {
+		errs.Add("year", "invalid", "year must be a four-digit year")
+	}
+	if req.Month < 1 || req.Month > 12 {
+		errs.Add("month", "invalid", "month must be between 1 and 12")
+	}
+	if len(errs) > 0 {
+		return updateError(c, "ClosePeriod", errs)
+	}
+	loc := transactionLocation()
+	period := report.Month(req.Year, time.Month(req.Month), loc)
+	now := time.Now()
+	if period.End.After(now) {
+		return updateError(c, "ClosePeriod", domain.ValidationErrors{
+			{Field: "month", Code: "not_ended", Message: "only months that have ended can be closed"},
+		})
+	}
+
+	var closed domain.ClosedPeriod
+	err := h.update(c, func(t domain.Repositories) error {
+		for _, p := range t.ClosedPeriods() {
+			if p.Locked() && p.Start.Before(period.End) && period.Start.Before(p.End) {
+				return &httpError{409, fmt.Sprintf("Period %s is already closed", p.Label)}
+			}
+		}
+		summary := domain.Summarize(t.Transactions(), t.Categories(), domain.SummaryQuery{
+			From:     &period.Start,
+			To:       &period.End,
+			Location: loc,
+			Interval: domain.IntervalMonth,
+		})
+		closed = domain.NewClosedPeriod(period.Start, period.End, period.Label, summary, actor(c), now)
+		closed.ID = domain.NewID()
+		closed.Note = domain.NormalizeText(req.Note)
+		if err := t.InsertClosedPeriod(closed); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "period",
+			EntityID:   closed.ID,
+			Action:     "close",
+			Note:       fmt.Sprintf("Closed period %s (closing balance %s)", closed.Label, closed.ClosingBalance),
+			After:      domain.AuditState(closed),
+			CreatedAt:  now,
+		})
+	})
+	if err != nil {
+		return updateError(c, "ClosePeriod", err)
+	}
+	return c.JSON(closed)
+}
+
+// ReopenPeriod unlocks a closed period. A reason is required; it is kept on
+// the period and in the audit log.
+func (h *Handler) ReopenPeriod(c *fiber.Ctx) error {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("ReopenPeriod BodyParser error: %v", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	reason := domain.NormalizeText(req.Reason)
+	if reason == "" {
+		return updateError(c, "ReopenPeriod", domain.ValidationErrors{
+			{Field: "reason", Code: "required", Message: "reason is required"},
+		})
+	}
+
+	id := c.Params("id")
+	var period domain.ClosedPeriod
+	err := h.update(c, func(t domain.Repositories) error {
+		var found bool
+		for _, p := range t.ClosedPeriods() {
+			if p.ID == id {
+				period, found = p, true
+			}
+		}
+		if !found {
+			return &httpError{404, "Period not found"}
+		}
+		if !period.Locked() {
+			return &httpError{409, fmt.Sprintf("Period %s is not closed", period.Label)}
+		}
+
+		previous := period
+		now := time.Now()
+		period.Reopen(reason, actor(c), now)
+		if err := t.UpdateClosedPeriod(period); err != nil {
+			return err
+		}
+		return t.InsertAuditLog(domain.AuditLog{
+			EntityType: "period",
+			EntityID:   period.ID,
+			Action:     "reopen",
+			Note:       fmt.Sprintf("Reopened period %s: %s", period.Label, reason),
+			Before:     domain.AuditState(previous),
+			After:      domain.AuditState(period),
+			CreatedAt:  now,
+		})
+	})
+	if err != nil {
+		return updateError(c, "ReopenPeriod", err)
+	}
+	return c.JSON(period)
+}
+
+// checkPeriodsOpen rejects a change touching a transaction dated in a
+// closed period. Corrections go in as adjusting entries instead.
+func checkPeriodsOpen(t domain.Repositories, dates ...time.Time) error {
+	periods := t.ClosedPeriods()
+	var closed []string
+	for _, d := range dates {
+		if p, ok := domain.LockedPeriod(periods, d); ok {
+			closed = append(closed, p.Label)
+		}
+	}
+	if len(closed) == 0 {
+		return nil
+	}
+	return &httpError{409, fmt.Sprintf("Period %s is closed; post the correction as an adjusting entry (adjustment_of) in an open period", strings.Join(closed, ", "))}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/query"
+	"audit-sendiri/internal/report"
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetCashReport returns the laporan kas as JSON, as a printable page with
+// format=html or as a signed-off PDF with format=pdf. The period is from/to,
+// a year with an optional month, or the current month when neither is given.
+func (h *Handler) GetCashReport(c *fiber.Ctx) error {
+	period, err := reportPeriod(c)
+	if err != nil {
+		return updateError(c, "GetCashReport", err)
+	}
+
+	var r *report.CashReport
+	err = h.Store.View(func(s domain.Snapshot) error {
+		r, err = report.Generate(s, period, time.Now().In(period.Start.Location()))
+		return err
+	})
+	if err != nil {
+		log.Printf("GetCashReport generate error: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+	}
+
+	switch c.Query("format", "json") {
+	case "json":
+		return c.JSON(r)
+	case "html":
+		var buf bytes.Buffer
+		if err := r.WriteHTML(&buf); err != nil {
+			log.Printf("GetCashReport render error: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(buf.Bytes())
+	case "pdf":
+		var buf bytes.Buffer
+		if err := r.WritePDF(&buf); err != nil {
+			log.Printf("GetCashReport PDF error: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Internal server error"})
+		}
+		filename := fmt.Sprintf("laporan-kas-RT%s-RW%s-%s.pdf", r.Header.RTName, r.Header.RWName, period.Start.Format("20060102"))
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", filename))
+		return c.Send(buf.Bytes())
+	}
+	return updateError(c, "GetCashReport", domain.ValidationErrors{
+		{Field: "format", Code: "invalid", Message: "format must be json, html or pdf"},
+	})
+}
+
+func reportPeriod(c *fiber.Ctx) (report.Period, error) {
+	var errs domain.ValidationErrors
+	r := query.ParseDateRange(c.Query, &errs)
+	loc := r.Location
+
+	if c.Query("from") != "" || c.Query("to") != "" {
+		if r.From == nil && c.Query("from") == "" {
+			errs.Add("from", "required", "from is required with to")
+		}
+		if r.To == nil && c.Query("to") == "" {
+			errs.Add("to", "required", "to is required with from")
+		}
+		if len(errs) > 0 {
+			return report.Period{}, errs
+		}
+		return report.Range(*r.From, *r.To), nil
+	}
+
+	now := time.Now().In(loc)
+	year, month := now.Year(), now.Month()
+	annual := false
+	if v := c.Query("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 1900 || y > 9999 {
+			errs.Add("year", "invalid", "year must be a four-digit year")
+		}
+		year = y
+		annual = c.Query("month") == ""
+	}
+	if v := c.Query("month"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m < 1 || m > 12 {
+			errs.Add("month", "invalid", "month must be between 1 and 12")
+		}
+		month = time.Month(m)
+	}
+	if len(errs) > 0 {
+		return report.Period{}, errs
+	}
+
+	if annual {
+		return report.Year(year, loc), nil
+	}
+	return report.Month(year, month, loc), nil
+}
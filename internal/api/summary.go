@@ -0,0 +1,33 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/query"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSummary returns totals, per-category breakdowns and a balance series
+// for the from/to/tz range, bucketed by the interval parameter (day, week
+// or month; month by default).
+func (h *Handler) GetSummary(c *fiber.Ctx) error {
+	var errs domain.ValidationErrors
+	r := query.ParseDateRange(c.Query, &errs)
+
+	interval := c.Query("interval", domain.IntervalMonth)
+	switch interval {
+	case domain.IntervalDay, domain.IntervalWeek, domain.IntervalMonth:
+	default:
+		errs.Add("interval", "invalid", "interval must be day, week or month")
+	}
+	if len(errs) > 0 {
+		return updateError(c, "GetSummary", errs)
+	}
+
+	return c.JSON(h.Store.Summary(domain.SummaryQuery{
+		From:     r.From,
+		To:       r.To,
+		Location: r.Location,
+		Interval: interval,
+	}))
+}
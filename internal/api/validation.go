@@ -0,0 +1,65 @@
+package api
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/query"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseTransactionInput decodes, normalizes and validates a transaction
+// body. Errors are ready for updateError: field problems come back as
+// domain.ValidationErrors, anything else as a 400 httpError. The parsed
+// transaction_date is returned separately and is nil when not given.
+func parseTransactionInput(c *fiber.Ctx, handler string, partial bool) (domain.TransactionInput, *time.Time, error) {
+	var in domain.TransactionInput
+	if err := c.BodyParser(&in); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.Is(err, domain.ErrInvalidAmount):
+			return in, nil, domain.ValidationErrors{{Field: "amount", Code: "invalid", Message: err.Error()}}
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return in, nil, domain.ValidationErrors{{Field: typeErr.Field, Code: "invalid_type", Message: typeErr.Field + " has the wrong type"}}
+		}
+		log.Printf("%s BodyParser error: %v", handler, err)
+		return in, nil, &httpError{400, "Invalid request format"}
+	}
+
+	in.Normalize()
+	errs := in.Validate(partial)
+	var date *time.Time
+	if in.TransactionDate != "" {
+		if d, err := domain.ParseTransactionDate(in.TransactionDate, transactionLocation()); err != nil {
+			errs.Add("transaction_date", "invalid", err.Error())
+		} else {
+			date = &d
+		}
+	}
+	if len(errs) > 0 {
+		return in, nil, errs
+	}
+	return in, date, nil
+}
+
+// sameTransactionDate reports whether an update leaves the date as it is. A
+// bare YYYY-MM-DD, as sent by date pickers, matches any time on that day.
+func sameTransactionDate(current, date time.Time, input string) bool {
+	if len(input) == len("2006-01-02") {
+		return current.In(transactionLocation()).Format("2006-01-02") == input
+	}
+	return current.Equal(date)
+}
+
+// transactionLocation is the timezone of date-only transaction dates and of
+// "today" in the backdating rules.
+func transactionLocation() *time.Location {
+	loc, err := time.LoadLocation(query.DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
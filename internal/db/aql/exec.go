@@ -0,0 +1,401 @@
+package aql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Result[T any] struct {
+	// Items holds the matched records after ordering, offset and limit.
+	Items []T
+	// Rows holds the projected fields when the statement names them
+	// explicitly instead of using *.
+	Rows []map[string]interface{}
+	// Total is the number of matches before offset and limit were applied.
+	Total int
+}
+
+// Execute runs sel against items. Fields are addressed by their JSON names.
+func Execute[T any](sel *Select, items []T) (Result[T], error) {
+	var result Result[T]
+	fields := fieldsOf(reflect.TypeOf((*T)(nil)).Elem())
+
+	ex := &executor{fields: fields, patterns: map[string]*regexp.Regexp{}}
+	if err := ex.prepare(sel); err != nil {
+		return result, err
+	}
+
+	matched := make([]int, 0, len(items))
+	for i := range items {
+		row := reflect.ValueOf(&items[i]).Elem()
+		if sel.Where == nil || ex.eval(sel.Where, row) {
+			matched = append(matched, i)
+		}
+	}
+	result.Total = len(matched)
+
+	if len(sel.OrderBy) > 0 {
+		keys := make([][]interface{}, len(items))
+		for _, i := range matched {
+			row := reflect.ValueOf(&items[i]).Elem()
+			k := make([]interface{}, len(sel.OrderBy))
+			for j, o := range sel.OrderBy {
+				k[j] = ex.value(row, o.Field)
+			}
+			keys[i] = k
+		}
+		sort.SliceStable(matched, func(a, b int) bool {
+			ka, kb := keys[matched[a]], keys[matched[b]]
+			for j, o := range sel.OrderBy {
+				c := orderCompare(ka[j], kb[j])
+				if c == 0 {
+					continue
+				}
+				if o.Desc {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+
+	if sel.Offset >= len(matched) {
+		matched = matched[:0]
+	} else {
+		matched = matched[sel.Offset:]
+	}
+	if sel.Limit >= 0 && sel.Limit < len(matched) {
+		matched = matched[:sel.Limit]
+	}
+
+	result.Items = make([]T, len(matched))
+	for j, i := range matched {
+		result.Items[j] = items[i]
+	}
+	if len(sel.Fields) > 0 {
+		result.Rows = make([]map[string]interface{}, len(matched))
+		for j, i := range matched {
+			row := reflect.ValueOf(&items[i]).Elem()
+			m := make(map[string]interface{}, len(sel.Fields))
+			for _, f := range sel.Fields {
+				m[f] = row.Field(fields[f]).Interface()
+			}
+			result.Rows[j] = m
+		}
+	}
+	return result, nil
+}
+
+var fieldCache sync.Map
+
+// fieldsOf maps the JSON names of a struct type to their field indexes.
+func fieldsOf(t reflect.Type) map[string]int {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]int)
+	}
+	fields := map[string]int{}
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fields[name] = i
+		}
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+type executor struct {
+	fields   map[string]int
+	patterns map[string]*regexp.Regexp
+}
+
+func (ex *executor) checkField(name string) error {
+	if _, ok := ex.fields[name]; !ok {
+		return fmt.Errorf("aql: unknown field %q", name)
+	}
+	return nil
+}
+
+func (ex *executor) prepare(sel *Select) error {
+	for _, f := range sel.Fields {
+		if err := ex.checkField(f); err != nil {
+			return err
+		}
+	}
+	for _, o := range sel.OrderBy {
+		if err := ex.checkField(o.Field); err != nil {
+			return err
+		}
+	}
+	if sel.Where != nil {
+		return ex.prepareExpr(sel.Where)
+	}
+	return nil
+}
+
+func (ex *executor) prepareExpr(e Expr) error {
+	switch e := e.(type) {
+	case And:
+		if err := ex.prepareExpr(e.Left); err != nil {
+			return err
+		}
+		return ex.prepareExpr(e.Right)
+	case Or:
+		if err := ex.prepareExpr(e.Left); err != nil {
+			return err
+		}
+		return ex.prepareExpr(e.Right)
+	case Not:
+		return ex.prepareExpr(e.Inner)
+	case Compare:
+		return ex.checkField(e.Field)
+	case In:
+		return ex.checkField(e.Field)
+	case IsNull:
+		return ex.checkField(e.Field)
+	case Like:
+		if _, ok := ex.patterns[e.Pattern]; !ok {
+			ex.patterns[e.Pattern] = likePattern(e.Pattern)
+		}
+		return ex.checkField(e.Field)
+	}
+	return fmt.Errorf("aql: unsupported expression %T", e)
+}
+
+// likePattern compiles a LIKE pattern: % matches any run of characters, _
+// matches one and a backslash makes the next character literal. Matching is
+// case-insensitive.
+func likePattern(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		if escaped {
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+			continue
+		}
+		switch r {
+		case '\\':
+			escaped = true
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		sb.WriteString(regexp.QuoteMeta(`\`))
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+func (ex *executor) value(row reflect.Value, field string) interface{} {
+	return normalize(row.Field(ex.fields[field]))
+}
+
+// Valuer lets a field type choose the value queries see, e.g. an amount
+// stored in minor units but compared in whole currency units. AQLValue must
+// return a string, float64, bool, time.Time or nil.
+type Valuer interface {
+	AQLValue() interface{}
+}
+
+func normalize(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if vv, ok := v.Interface().(Valuer); ok {
+		return vv.AQLValue()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func (ex *executor) eval(e Expr, row reflect.Value) bool {
+	switch e := e.(type) {
+	case And:
+		return ex.eval(e.Left, row) && ex.eval(e.Right, row)
+	case Or:
+		return ex.eval(e.Left, row) || ex.eval(e.Right, row)
+	case Not:
+		return !ex.eval(e.Inner, row)
+	case IsNull:
+		return ex.value(row, e.Field) == nil
+	case Like:
+		v := ex.value(row, e.Field)
+		if v == nil {
+			return false
+		}
+		return ex.patterns[e.Pattern].MatchString(toString(v))
+	case In:
+		v := ex.value(row, e.Field)
+		for _, candidate := range e.Values {
+			if c, ok := compare(v, candidate); ok && c == 0 {
+				return true
+			}
+		}
+		return false
+	case Compare:
+		c, ok := compare(ex.value(row, e.Field), e.Value)
+		if !ok {
+			return e.Op == "!="
+		}
+		switch e.Op {
+		case "=":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		case ">=":
+			return c >= 0
+		}
+	}
+	return false
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compare orders a record value against a literal. The second result is
+// false when the two cannot be compared, e.g. a string field against a
+// number, or anything against KOSONG except KOSONG itself.
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, a == nil && b == nil
+	}
+	switch a := a.(type) {
+	case string:
+		switch b := b.(type) {
+		case string:
+			return strings.Compare(a, b), true
+		case float64:
+			f, err := strconv.ParseFloat(a, 64)
+			if err != nil {
+				return 0, false
+			}
+			return compareFloat(f, b), true
+		}
+	case float64:
+		switch b := b.(type) {
+		case float64:
+			return compareFloat(a, b), true
+		case string:
+			f, err := strconv.ParseFloat(b, 64)
+			if err != nil {
+				return 0, false
+			}
+			return compareFloat(a, f), true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case !a:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case time.Time:
+		switch b := b.(type) {
+		case time.Time:
+			return a.Compare(b), true
+		case string:
+			t, ok := parseTime(b)
+			if !ok {
+				return 0, false
+			}
+			return a.Compare(t), true
+		}
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// orderCompare is compare with a total order for sorting: KOSONG sorts
+// first and incomparable values keep their relative order.
+func orderCompare(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	c, _ := compare(a, b)
+	return c
+}
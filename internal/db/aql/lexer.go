@@ -0,0 +1,157 @@
+package aql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokKeyword
+	tokString
+	tokNumber
+	tokOp
+	tokComma
+	tokLParen
+	tokRParen
+	tokStar
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// keywords maps every accepted spelling to its canonical AQL keyword, so the
+// Indonesian dialect and the SQL spelling can be mixed freely.
+var keywords = map[string]string{
+	"PANEN":       "PANEN",
+	"SELECT":      "PANEN",
+	"DARI":        "DARI",
+	"FROM":        "DARI",
+	"JSON":        "JSON",
+	"DIMANA":      "DIMANA",
+	"WHERE":       "DIMANA",
+	"DAN":         "DAN",
+	"AND":         "DAN",
+	"ATAU":        "ATAU",
+	"OR":          "ATAU",
+	"BUKAN":       "BUKAN",
+	"NOT":         "BUKAN",
+	"SEPERTI":     "SEPERTI",
+	"LIKE":        "SEPERTI",
+	"DALAM":       "DALAM",
+	"IN":          "DALAM",
+	"ADALAH":      "ADALAH",
+	"IS":          "ADALAH",
+	"KOSONG":      "KOSONG",
+	"NULL":        "KOSONG",
+	"BENAR":       "BENAR",
+	"TRUE":        "BENAR",
+	"SALAH":       "SALAH",
+	"FALSE":       "SALAH",
+	"URUT":        "URUT",
+	"ORDER":       "URUT",
+	"BERDASARKAN": "BERDASARKAN",
+	"BY":          "BERDASARKAN",
+	"NAIK":        "NAIK",
+	"ASC":         "NAIK",
+	"TURUN":       "TURUN",
+	"DESC":        "TURUN",
+	"BATAS":       "BATAS",
+	"LIMIT":       "BATAS",
+	"LEWATI":      "LEWATI",
+	"OFFSET":      "LEWATI",
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '*':
+			tokens = append(tokens, token{tokStar, "*", i})
+			i++
+		case c == '=':
+			tokens = append(tokens, token{tokOp, "=", i})
+			i++
+		case c == '!' || c == '<' || c == '>':
+			start := i
+			i++
+			if i < len(src) && (src[i] == '=' || (c == '<' && src[i] == '>')) {
+				i++
+			}
+			op := src[start:i]
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at position %d", start)
+			}
+			if op == "<>" {
+				op = "!="
+			}
+			tokens = append(tokens, token{tokOp, op, start})
+		case c == '\'' || c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(src) {
+				if src[i] == c {
+					// A doubled quote is an escaped quote character.
+					if i+1 < len(src) && src[i+1] == c {
+						sb.WriteByte(c)
+						i += 2
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string starting at position %d", start)
+			}
+			tokens = append(tokens, token{tokString, sb.String(), start})
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(src) && (src[i] == '.' || (src[i] >= '0' && src[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '.' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			word := src[start:i]
+			if kw, ok := keywords[strings.ToUpper(word)]; ok {
+				tokens = append(tokens, token{tokKeyword, kw, start})
+			} else {
+				tokens = append(tokens, token{tokIdent, word, start})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	tokens = append(tokens, token{tokEOF, "", len(src)})
+	return tokens, nil
+}
@@ -0,0 +1,374 @@
+package aql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Select is a parsed PANEN statement:
+//
+//	PANEN <* | field, ...> DARI [JSON] <table>
+//	  [DIMANA <condition>]
+//	  [URUT BERDASARKAN <field> [NAIK|TURUN], ...]
+//	  [BATAS <n>] [LEWATI <n>]
+//
+// Conditions combine comparisons (=, !=, <>, <, <=, >, >=), SEPERTI (LIKE),
+// DALAM (IN) and ADALAH [BUKAN] KOSONG (IS [NOT] NULL) with DAN, ATAU, BUKAN
+// and parentheses. SQL spellings of every keyword are accepted as well.
+type Select struct {
+	Fields  []string
+	Table   string
+	Where   Expr
+	OrderBy []Order
+	Limit   int
+	Offset  int
+}
+
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+type Expr interface {
+	expr()
+}
+
+type And struct{ Left, Right Expr }
+type Or struct{ Left, Right Expr }
+type Not struct{ Inner Expr }
+
+type Compare struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+type Like struct {
+	Field   string
+	Pattern string
+}
+
+type In struct {
+	Field  string
+	Values []interface{}
+}
+
+type IsNull struct {
+	Field string
+}
+
+func (And) expr()     {}
+func (Or) expr()      {}
+func (Not) expr()     {}
+func (Compare) expr() {}
+func (Like) expr()    {}
+func (In) expr()      {}
+func (IsNull) expr()  {}
+
+// IsQuery reports whether statement is a read-only PANEN statement.
+func IsQuery(statement string) bool {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return false
+	}
+	return keywords[strings.ToUpper(fields[0])] == "PANEN"
+}
+
+func Parse(src string) (*Select, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	sel, err := p.parseSelect()
+	if err != nil {
+		return nil, fmt.Errorf("aql: %w", err)
+	}
+	return sel, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokKeyword && t.text == kw
+}
+
+func (p *parser) acceptKeyword(kw string) bool {
+	if p.isKeyword(kw) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return p.errorf("expected %s", kw)
+	}
+	return nil
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s at position %d, got %q", what, t.pos, t.text)
+	}
+	return t, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	got := t.text
+	if t.kind == tokEOF {
+		got = "end of statement"
+	}
+	return fmt.Errorf("%s at position %d, got %q", fmt.Sprintf(format, args...), t.pos, got)
+}
+
+func (p *parser) parseSelect() (*Select, error) {
+	if err := p.expectKeyword("PANEN"); err != nil {
+		return nil, err
+	}
+	sel := &Select{Limit: -1}
+
+	if p.peek().kind == tokStar {
+		p.next()
+	} else {
+		for {
+			t, err := p.expect(tokIdent, "field name")
+			if err != nil {
+				return nil, err
+			}
+			sel.Fields = append(sel.Fields, t.text)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if err := p.expectKeyword("DARI"); err != nil {
+		return nil, err
+	}
+	p.acceptKeyword("JSON")
+	table, err := p.expect(tokIdent, "table name")
+	if err != nil {
+		return nil, err
+	}
+	sel.Table = strings.ToLower(table.text)
+
+	if p.acceptKeyword("DIMANA") {
+		if sel.Where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("URUT") {
+		if err := p.expectKeyword("BERDASARKAN"); err != nil {
+			return nil, err
+		}
+		for {
+			t, err := p.expect(tokIdent, "field name")
+			if err != nil {
+				return nil, err
+			}
+			order := Order{Field: t.text}
+			if p.acceptKeyword("TURUN") {
+				order.Desc = true
+			} else {
+				p.acceptKeyword("NAIK")
+			}
+			sel.OrderBy = append(sel.OrderBy, order)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if p.acceptKeyword("BATAS") {
+		if sel.Limit, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("LEWATI") {
+		if sel.Offset, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return sel, nil
+}
+
+func (p *parser) parseCount() (int, error) {
+	t, err := p.expect(tokNumber, "number")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(t.text)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid count %q at position %d", t.text, t.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("ATAU") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("DAN") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.acceptKeyword("BUKAN") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	field, err := p.expect(tokIdent, "field name")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("ADALAH") {
+		negate := p.acceptKeyword("BUKAN")
+		if err := p.expectKeyword("KOSONG"); err != nil {
+			return nil, err
+		}
+		var e Expr = IsNull{field.text}
+		if negate {
+			e = Not{e}
+		}
+		return e, nil
+	}
+
+	negate := p.acceptKeyword("BUKAN")
+	var e Expr
+	switch {
+	case p.acceptKeyword("SEPERTI"):
+		t, err := p.expect(tokString, "pattern string")
+		if err != nil {
+			return nil, err
+		}
+		e = Like{field.text, t.text}
+	case p.acceptKeyword("DALAM"):
+		if _, err := p.expect(tokLParen, "'('"); err != nil {
+			return nil, err
+		}
+		in := In{Field: field.text}
+		for {
+			v, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			in.Values = append(in.Values, v)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		e = in
+	default:
+		if negate {
+			return nil, p.errorf("expected SEPERTI or DALAM after BUKAN")
+		}
+		op, err := p.expect(tokOp, "comparison operator")
+		if err != nil {
+			return nil, err
+		}
+		v, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		e = Compare{field.text, op.text, v}
+	}
+	if negate {
+		e = Not{e}
+	}
+	return e, nil
+}
+
+func (p *parser) parseLiteral() (interface{}, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokString:
+		return t.text, nil
+	case t.kind == tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return f, nil
+	case t.kind == tokKeyword && t.text == "BENAR":
+		return true, nil
+	case t.kind == tokKeyword && t.text == "SALAH":
+		return false, nil
+	case t.kind == tokKeyword && t.text == "KOSONG":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("expected literal at position %d, got %q", t.pos, t.text)
+}
@@ -0,0 +1,124 @@
+package aql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    []token
+		wantErr string
+	}{
+		{
+			src: "PANEN * DARI transactions",
+			want: []token{
+				{tokKeyword, "PANEN", 0}, {tokStar, "*", 6}, {tokKeyword, "DARI", 8},
+				{tokIdent, "transactions", 13}, {tokEOF, "", 25},
+			},
+		},
+		{
+			src: "select amount from",
+			want: []token{
+				{tokKeyword, "PANEN", 0}, {tokIdent, "amount", 7}, {tokKeyword, "DARI", 14}, {tokEOF, "", 18},
+			},
+		},
+		{
+			src: "a<>1 b<=-2.5 c!=d",
+			want: []token{
+				{tokIdent, "a", 0}, {tokOp, "!=", 1}, {tokNumber, "1", 3},
+				{tokIdent, "b", 5}, {tokOp, "<=", 6}, {tokNumber, "-2.5", 8},
+				{tokIdent, "c", 13}, {tokOp, "!=", 14}, {tokIdent, "d", 16}, {tokEOF, "", 17},
+			},
+		},
+		{
+			src:  `'it''s' "say ""hi"""`,
+			want: []token{{tokString, "it's", 0}, {tokString, `say "hi"`, 8}, {tokEOF, "", 20}},
+		},
+		{
+			src:  "(x, created_by.id)",
+			want: []token{{tokLParen, "(", 0}, {tokIdent, "x", 1}, {tokComma, ",", 2}, {tokIdent, "created_by.id", 4}, {tokRParen, ")", 17}, {tokEOF, "", 18}},
+		},
+		{src: "a ! b", wantErr: "unexpected '!' at position 2"},
+		{src: "'open", wantErr: "unterminated string starting at position 0"},
+		{src: "a; b", wantErr: "unexpected character ';' at position 1"},
+	}
+	for _, tt := range tests {
+		got, err := tokenize(tt.src)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("tokenize(%q) error = %v, want %q", tt.src, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %v, %v; want %v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    *Select
+		wantErr string
+	}{
+		{
+			src:  "PANEN * DARI JSON Transactions",
+			want: &Select{Table: "transactions", Limit: -1},
+		},
+		{
+			src:  "SELECT id, amount FROM transactions LIMIT 10 OFFSET 20",
+			want: &Select{Fields: []string{"id", "amount"}, Table: "transactions", Limit: 10, Offset: 20},
+		},
+		{
+			src: "PANEN * DARI transactions DIMANA type = 'income' DAN amount >= 1000 ATAU category SEPERTI 'Iuran%'",
+			want: &Select{Table: "transactions", Limit: -1, Where: Or{
+				And{Compare{"type", "=", "income"}, Compare{"amount", ">=", 1000.0}},
+				Like{"category", "Iuran%"},
+			}},
+		},
+		{
+			src: "PANEN * DARI transactions DIMANA BUKAN (a = 1 ATAU b = BENAR) DAN c BUKAN DALAM ('x', 2, KOSONG)",
+			want: &Select{Table: "transactions", Limit: -1, Where: And{
+				Not{Or{Compare{"a", "=", 1.0}, Compare{"b", "=", true}}},
+				Not{In{"c", []interface{}{"x", 2.0, nil}}},
+			}},
+		},
+		{
+			src: "PANEN * DARI transactions WHERE deleted_at IS NULL AND updated_at IS NOT NULL",
+			want: &Select{Table: "transactions", Limit: -1, Where: And{
+				IsNull{"deleted_at"}, Not{IsNull{"updated_at"}},
+			}},
+		},
+		{
+			src: "PANEN * DARI transactions URUT BERDASARKAN date TURUN, id",
+			want: &Select{Table: "transactions", Limit: -1, OrderBy: []Order{
+				{Field: "date", Desc: true}, {Field: "id"},
+			}},
+		},
+		{src: "", wantErr: `aql: expected PANEN at position 0, got "end of statement"`},
+		{src: "PANEN * transactions", wantErr: `expected DARI`},
+		{src: "PANEN * DARI transactions DIMANA a BUKAN = 1", wantErr: "expected SEPERTI or DALAM after BUKAN"},
+		{src: "PANEN * DARI transactions DIMANA (a = 1", wantErr: "expected ')'"},
+		{src: "PANEN * DARI transactions DIMANA a = ", wantErr: "expected literal"},
+		{src: "PANEN * DARI transactions BATAS -1", wantErr: `invalid count "-1"`},
+		{src: "PANEN * DARI transactions BATAS 1.5", wantErr: `invalid count "1.5"`},
+		{src: "PANEN * DARI transactions LEWATI 1 BATAS 2", wantErr: "unexpected token"},
+		{src: "PANEN * DARI transactions DIMANA a = '1", wantErr: "unterminated string"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.src)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.src, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", tt.src, got, err, tt.want)
+		}
+	}
+}
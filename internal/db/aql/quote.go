@@ -0,0 +1,16 @@
+package aql
+
+import "strings"
+
+// Quote renders s as a string literal, doubling any single quotes, so
+// callers can build statements from untrusted input.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes the LIKE wildcards in s so it only matches itself.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
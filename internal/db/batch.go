@@ -0,0 +1,161 @@
+package db
+
+import (
+	"audit-sendiri/internal/db/aql"
+	"audit-sendiri/internal/domain"
+	"encoding/json"
+	"fmt"
+)
+
+// batchPrefix frames several statements as one log record. The record is
+// checksummed as a whole, so Rehydrate applies either all of them or none.
+const batchPrefix = "SERENTAK "
+
+// Batch collects write statements that are committed together. Nothing is
+// written or applied until the batch commits.
+type Batch struct {
+	statements []string
+	// auditHead is the hash of the newest audit entry, staged or committed,
+	// that the next InsertAuditLog chains onto.
+	auditHead string
+}
+
+func (b *Batch) Execute(statement string) error {
+	if aql.IsQuery(statement) {
+		return fmt.Errorf("read-only statement in batch: %s", statement)
+	}
+	b.statements = append(b.statements, statement)
+	return nil
+}
+
+func (b *Batch) writeJSON(op, table string, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", table, err)
+	}
+	return b.Execute(fmt.Sprintf("%s JSON %s %s", op, table, string(payload)))
+}
+
+func (b *Batch) InsertTransaction(tx domain.Transaction) error {
+	return b.writeJSON("TANAM", "transactions", tx)
+}
+
+func (b *Batch) UpdateTransaction(tx domain.Transaction) error {
+	return b.writeJSON("UBAH", "transactions", tx)
+}
+
+func (b *Batch) InsertUser(u domain.User) error {
+	return b.writeJSON("TANAM", "users", u)
+}
+
+func (b *Batch) UpdateUser(u domain.User) error {
+	return b.writeJSON("UBAH", "users", u)
+}
+
+func (b *Batch) DeleteUser(id string) error {
+	return b.writeJSON("HAPUS", "users", map[string]string{"id": id})
+}
+
+func (b *Batch) InsertCategory(c domain.Category) error {
+	return b.writeJSON("TANAM", "categories", c)
+}
+
+func (b *Batch) UpdateCategory(c domain.Category) error {
+	return b.writeJSON("UBAH", "categories", c)
+}
+
+func (b *Batch) DeleteCategory(id string) error {
+	return b.writeJSON("HAPUS", "categories", map[string]string{"id": id})
+}
+
+func (b *Batch) InsertAuditLog(log domain.AuditLog) error {
+	if log.ID == "" {
+		log.ID = domain.NewID()
+	}
+	// Only entries that record entity state are in the current schema;
+	// events such as logins have none.
+	if log.SchemaVersion == 0 && (log.Before != nil || log.After != nil) {
+		log.SchemaVersion = domain.AuditSchemaVersion
+	}
+	log.Seal(b.auditHead)
+	if err := b.writeJSON("TANAM", "audit_log", log); err != nil {
+		return err
+	}
+	b.auditHead = log.Hash
+	return nil
+}
+
+func (b *Batch) InsertClosedPeriod(p domain.ClosedPeriod) error {
+	return b.writeJSON("TANAM", "closed_periods", p)
+}
+
+func (b *Batch) UpdateClosedPeriod(p domain.ClosedPeriod) error {
+	return b.writeJSON("UBAH", "closed_periods", p)
+}
+
+func (b *Batch) SaveSettings(s domain.AppSettings) error {
+	return b.writeJSON("TANAM", "settings", s)
+}
+
+// Tx is the domain.Repositories handed to Update, and the domain.Snapshot
+// handed to View: the write methods of Batch plus reads of the locked state.
+type Tx struct {
+	Batch
+	engine *engine
+}
+
+func (tx *Tx) Transactions() []domain.Transaction {
+	return tx.engine.copyTransactions()
+}
+
+func (tx *Tx) Transaction(id string) (domain.Transaction, bool) {
+	return tx.engine.findTransaction(id)
+}
+
+func (tx *Tx) QueryTransactions(query string) ([]domain.Transaction, int, error) {
+	return tx.engine.queryTransactions(query)
+}
+
+func (tx *Tx) AuditLogs() []domain.AuditLog {
+	return tx.engine.copyAuditLogs()
+}
+
+func (tx *Tx) AuditLog(id string) (domain.AuditLog, bool) {
+	return tx.engine.findAuditLog(id)
+}
+
+func (tx *Tx) Users() []domain.User {
+	return tx.engine.copyUsers()
+}
+
+func (tx *Tx) User(id string) (domain.User, bool) {
+	return tx.engine.findUser(id)
+}
+
+func (tx *Tx) UserByUsername(username string) (domain.User, bool) {
+	return tx.engine.findUserByUsername(username)
+}
+
+func (tx *Tx) UserCount() int {
+	return len(tx.engine.users)
+}
+
+func (tx *Tx) Categories() []domain.Category {
+	return tx.engine.copyCategories()
+}
+
+func (tx *Tx) Category(id string) (domain.Category, bool) {
+	return tx.engine.findCategory(id)
+}
+
+func (tx *Tx) ClosedPeriods() []domain.ClosedPeriod {
+	return tx.engine.copyClosedPeriods()
+}
+
+func (tx *Tx) Settings() domain.AppSettings {
+	return tx.engine.settings
+}
+
+func (tx *Tx) Summary(q domain.SummaryQuery) domain.Summary {
+	return tx.engine.summary(q)
+}
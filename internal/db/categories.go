@@ -0,0 +1,103 @@
+package db
+
+import (
+	"audit-sendiri/internal/domain"
+	"fmt"
+	"time"
+)
+
+// migrateCategories links transactions that only carry a free-text category
+// to category master data. Spelling variants that differ only in case or
+// whitespace end up in one category, named after the most common variant.
+func migrateCategories(t domain.Repositories, now time.Time) error {
+	type group struct{ kind, key string }
+
+	var pending []domain.Transaction
+	variants := map[group]map[string]int{}
+	var order []group
+	for _, tx := range t.Transactions() {
+		if tx.CategoryID != "" || (tx.Type != "income" && tx.Type != "expense") {
+			continue
+		}
+		pending = append(pending, tx)
+		name := domain.NormalizeCategoryName(tx.Category)
+		g := group{tx.Type, domain.CategoryKey(name)}
+		if variants[g] == nil {
+			variants[g] = map[string]int{}
+			order = append(order, g)
+		}
+		variants[g][name]++
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	categories := t.Categories()
+	resolved := map[group]domain.Category{}
+	created := 0
+	for _, g := range order {
+		if c, ok := findCategoryByKey(categories, g.kind, g.key); ok {
+			resolved[g] = c
+			continue
+		}
+
+		name := ""
+		best := 0
+		for variant, n := range variants[g] {
+			if n > best || (n == best && variant < name) {
+				name, best = variant, n
+			}
+		}
+		if name == "" {
+			name = "Pemasukan Lain-lain"
+			if g.kind == "expense" {
+				name = "Pengeluaran Lain-lain"
+			}
+			if c, ok := findCategoryByKey(categories, g.kind, domain.CategoryKey(name)); ok {
+				resolved[g] = c
+				continue
+			}
+		}
+
+		c := domain.Category{
+			ID:        domain.NewID(),
+			Kind:      g.kind,
+			Code:      domain.UniqueCategoryCode(categories, domain.CategoryCode(name)),
+			Name:      name,
+			Active:    true,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := t.InsertCategory(c); err != nil {
+			return err
+		}
+		categories = append(categories, c)
+		resolved[g] = c
+		created++
+	}
+
+	for _, tx := range pending {
+		c := resolved[group{tx.Type, domain.CategoryKey(tx.Category)}]
+		tx.CategoryID = c.ID
+		tx.Category = c.Name
+		if err := t.UpdateTransaction(tx); err != nil {
+			return err
+		}
+	}
+
+	return t.InsertAuditLog(domain.AuditLog{
+		EntityType: "category",
+		Action:     "migrate",
+		Note:       fmt.Sprintf("Linked %d transactions with free-text categories to %d categories (%d created)", len(pending), len(resolved), created),
+		CreatedAt:  now,
+	})
+}
+
+func findCategoryByKey(categories []domain.Category, kind, key string) (domain.Category, bool) {
+	for _, c := range categories {
+		if c.Kind == kind && domain.CategoryKey(c.Name) == key {
+			return c, true
+		}
+	}
+	return domain.Category{}, false
+}
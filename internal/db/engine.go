@@ -0,0 +1,518 @@
+package db
+
+import (
+	"audit-sendiri/internal/db/aql"
+	"audit-sendiri/internal/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// engine is the in-memory side shared by SawitDB and MemoryStore: the
+// tables, the lock that guards them and the repository API on top. commit is
+// called with mu held and must make the statements durable (if the store
+// has a disk) before applying them.
+type engine struct {
+	mu            sync.RWMutex
+	transactions  []domain.Transaction
+	auditLogs     []domain.AuditLog
+	users         []domain.User
+	categories    []domain.Category
+	closedPeriods []domain.ClosedPeriod
+	settings      domain.AppSettings
+	tables        map[string]bool
+
+	// revision counts applied statements, so derived data such as cached
+	// summaries can tell when it is stale.
+	revision  uint64
+	summaries *summaryCache
+
+	commit func(statements []string) error
+}
+
+func newEngine() engine {
+	return engine{
+		transactions:  []domain.Transaction{},
+		auditLogs:     []domain.AuditLog{},
+		users:         []domain.User{},
+		categories:    []domain.Category{},
+		closedPeriods: []domain.ClosedPeriod{},
+		settings:      domain.AppSettings{RTName: "001", RWName: "001"},
+		tables:        map[string]bool{},
+		summaries:     &summaryCache{},
+	}
+}
+
+func (e *engine) applyLocally(aql string) {
+	e.revision++
+	if strings.HasPrefix(aql, batchPrefix) {
+		e.applyBatch(aql)
+		return
+	}
+
+	if strings.HasPrefix(aql, "LAHAN ") {
+		e.tables[strings.TrimSpace(strings.TrimPrefix(aql, "LAHAN "))] = true
+		return
+	}
+
+	if strings.HasPrefix(aql, "TANAM JSON") || strings.HasPrefix(aql, "UBAH JSON") {
+		op := "TANAM"
+		if strings.HasPrefix(aql, "UBAH JSON") {
+			op = "UBAH"
+		}
+
+		parts := strings.SplitN(aql, " ", 4)
+		if len(parts) < 4 {
+			return
+		}
+		tableName := parts[2]
+		payload := parts[3]
+
+		switch tableName {
+		case "transactions":
+			if tx, err := decodeTransaction([]byte(payload)); err == nil {
+				if op == "TANAM" {
+					e.transactions = append(e.transactions, tx)
+				} else {
+					for i, t := range e.transactions {
+						if t.ID == tx.ID {
+							e.transactions[i] = tx
+							break
+						}
+					}
+				}
+			}
+		case "users":
+			var user domain.User
+			if err := json.Unmarshal([]byte(payload), &user); err == nil {
+				if op == "TANAM" {
+					e.users = append(e.users, user)
+				} else {
+					for i, u := range e.users {
+						if u.ID == user.ID {
+							e.users[i] = user
+							break
+						}
+					}
+				}
+			} else {
+				log.Printf("Failed to unmarshal user: %v", err)
+			}
+		case "categories":
+			var c domain.Category
+			if err := json.Unmarshal([]byte(payload), &c); err == nil {
+				if op == "TANAM" {
+					e.categories = append(e.categories, c)
+				} else {
+					for i, existing := range e.categories {
+						if existing.ID == c.ID {
+							e.categories[i] = c
+							break
+						}
+					}
+				}
+			} else {
+				log.Printf("Failed to unmarshal category: %v", err)
+			}
+		case "audit_log":
+			var auditLog domain.AuditLog
+			if err := json.Unmarshal([]byte(payload), &auditLog); err == nil {
+				if auditLog.ID == "" {
+					auditLog.ID = domain.NewID()
+				}
+				if op == "TANAM" {
+					e.auditLogs = append(e.auditLogs, auditLog)
+				}
+			}
+		case "closed_periods":
+			var p domain.ClosedPeriod
+			if err := json.Unmarshal([]byte(payload), &p); err == nil {
+				if op == "TANAM" {
+					e.closedPeriods = append(e.closedPeriods, p)
+				} else {
+					for i, existing := range e.closedPeriods {
+						if existing.ID == p.ID {
+							e.closedPeriods[i] = p
+							break
+						}
+					}
+				}
+			} else {
+				log.Printf("Failed to unmarshal closed period: %v", err)
+			}
+		case "settings":
+			var s domain.AppSettings
+			if err := json.Unmarshal([]byte(payload), &s); err == nil {
+				e.settings = s
+			}
+		}
+		return
+	}
+
+	if strings.HasPrefix(aql, "HAPUS JSON") {
+		parts := strings.SplitN(aql, " ", 4)
+		if len(parts) < 4 {
+			return
+		}
+		tableName := parts[2]
+		idRaw := parts[3]
+		type IDWrapper struct {
+			ID string `json:"id"`
+		}
+		var idObj IDWrapper
+		if err := json.Unmarshal([]byte(idRaw), &idObj); err != nil {
+			return
+		}
+
+		switch tableName {
+		case "users":
+			newUsers := []domain.User{}
+			for _, u := range e.users {
+				if u.ID != idObj.ID {
+					newUsers = append(newUsers, u)
+				}
+			}
+			e.users = newUsers
+		case "categories":
+			newCategories := []domain.Category{}
+			for _, c := range e.categories {
+				if c.ID != idObj.ID {
+					newCategories = append(newCategories, c)
+				}
+			}
+			e.categories = newCategories
+		}
+		return
+	}
+}
+
+// decodeTransaction reads a transaction record. Records written while
+// amounts were float64 can hold fractions of a sen; those amounts are
+// rounded to the nearest sen. Records without a transaction date get their
+// creation time, so queries can filter on transaction_date alone.
+func decodeTransaction(data []byte) (domain.Transaction, error) {
+	tx, err := decodeTransactionAmount(data)
+	if err == nil && tx.TransactionDate == nil {
+		date := tx.CreatedAt
+		tx.TransactionDate = &date
+	}
+	return tx, err
+}
+
+func decodeTransactionAmount(data []byte) (domain.Transaction, error) {
+	var tx domain.Transaction
+	err := json.Unmarshal(data, &tx)
+	if !errors.Is(err, domain.ErrInvalidAmount) {
+		return tx, err
+	}
+
+	var legacy struct {
+		domain.Transaction
+		Amount float64 `json:"amount"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return tx, err
+	}
+	tx = legacy.Transaction
+	tx.Amount, err = domain.MoneyFromFloat(legacy.Amount)
+	return tx, err
+}
+
+// transactionList decodes with decodeTransaction, for snapshots written
+// before amounts were stored in sen.
+type transactionList []domain.Transaction
+
+func (l *transactionList) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*l = make(transactionList, 0, len(raw))
+	for _, r := range raw {
+		tx, err := decodeTransaction(r)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, tx)
+	}
+	return nil
+}
+
+func (e *engine) applyBatch(record string) {
+	var statements []string
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(record, batchPrefix)), &statements); err != nil {
+		log.Printf("Failed to decode batch record: %v", err)
+		return
+	}
+	for _, s := range statements {
+		e.applyLocally(s)
+	}
+}
+
+// Update implements domain.UnitOfWork.
+func (e *engine) Update(fn func(tx domain.Repositories) error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tx := &Tx{Batch: Batch{auditHead: e.auditHead()}, engine: e}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return e.commit(tx.statements)
+}
+
+// View implements domain.UnitOfWork.
+func (e *engine) View(fn func(s domain.Snapshot) error) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return fn(&Tx{engine: e})
+}
+
+// batch is Update for callers that only write.
+func (e *engine) batch(fn func(b *Batch) error) error {
+	return e.Update(func(tx domain.Repositories) error {
+		return fn(&tx.(*Tx).Batch)
+	})
+}
+
+func (e *engine) InsertTransaction(tx domain.Transaction) error {
+	return e.batch(func(b *Batch) error { return b.InsertTransaction(tx) })
+}
+
+func (e *engine) UpdateTransaction(tx domain.Transaction) error {
+	return e.batch(func(b *Batch) error { return b.UpdateTransaction(tx) })
+}
+
+func (e *engine) InsertUser(u domain.User) error {
+	return e.batch(func(b *Batch) error { return b.InsertUser(u) })
+}
+
+func (e *engine) UpdateUser(u domain.User) error {
+	return e.batch(func(b *Batch) error { return b.UpdateUser(u) })
+}
+
+func (e *engine) DeleteUser(id string) error {
+	return e.batch(func(b *Batch) error { return b.DeleteUser(id) })
+}
+
+func (e *engine) InsertCategory(c domain.Category) error {
+	return e.batch(func(b *Batch) error { return b.InsertCategory(c) })
+}
+
+func (e *engine) UpdateCategory(c domain.Category) error {
+	return e.batch(func(b *Batch) error { return b.UpdateCategory(c) })
+}
+
+func (e *engine) DeleteCategory(id string) error {
+	return e.batch(func(b *Batch) error { return b.DeleteCategory(id) })
+}
+
+func (e *engine) InsertAuditLog(log domain.AuditLog) error {
+	return e.batch(func(b *Batch) error { return b.InsertAuditLog(log) })
+}
+
+func (e *engine) InsertClosedPeriod(p domain.ClosedPeriod) error {
+	return e.batch(func(b *Batch) error { return b.InsertClosedPeriod(p) })
+}
+
+func (e *engine) UpdateClosedPeriod(p domain.ClosedPeriod) error {
+	return e.batch(func(b *Batch) error { return b.UpdateClosedPeriod(p) })
+}
+
+func (e *engine) SaveSettings(s domain.AppSettings) error {
+	return e.batch(func(b *Batch) error { return b.SaveSettings(s) })
+}
+
+// The accessors below return copies, so callers can hold on to or modify
+// the results without racing against writers.
+
+func (e *engine) Transactions() []domain.Transaction {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.copyTransactions()
+}
+
+func (e *engine) Transaction(id string) (domain.Transaction, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.findTransaction(id)
+}
+
+func (e *engine) QueryTransactions(query string) ([]domain.Transaction, int, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.queryTransactions(query)
+}
+
+func (e *engine) AuditLogs() []domain.AuditLog {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.copyAuditLogs()
+}
+
+func (e *engine) AuditLog(id string) (domain.AuditLog, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.findAuditLog(id)
+}
+
+func (e *engine) Users() []domain.User {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.copyUsers()
+}
+
+func (e *engine) User(id string) (domain.User, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.findUser(id)
+}
+
+func (e *engine) UserByUsername(username string) (domain.User, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.findUserByUsername(username)
+}
+
+func (e *engine) UserCount() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.users)
+}
+
+func (e *engine) Categories() []domain.Category {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.copyCategories()
+}
+
+func (e *engine) Category(id string) (domain.Category, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.findCategory(id)
+}
+
+func (e *engine) Settings() domain.AppSettings {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.settings
+}
+
+func (e *engine) ClosedPeriods() []domain.ClosedPeriod {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.copyClosedPeriods()
+}
+
+// Unlocked helpers; callers hold mu.
+
+func (e *engine) copyClosedPeriods() []domain.ClosedPeriod {
+	return append([]domain.ClosedPeriod(nil), e.closedPeriods...)
+}
+
+func (e *engine) copyTransactions() []domain.Transaction {
+	return append([]domain.Transaction(nil), e.transactions...)
+}
+
+func (e *engine) copyAuditLogs() []domain.AuditLog {
+	return append([]domain.AuditLog(nil), e.auditLogs...)
+}
+
+func (e *engine) copyUsers() []domain.User {
+	return append([]domain.User(nil), e.users...)
+}
+
+func (e *engine) copyCategories() []domain.Category {
+	return append([]domain.Category(nil), e.categories...)
+}
+
+func (e *engine) queryTransactions(query string) ([]domain.Transaction, int, error) {
+	sel, err := aql.Parse(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if sel.Table != "transactions" {
+		return nil, 0, fmt.Errorf("aql: expected table transactions, got %q", sel.Table)
+	}
+	res, err := aql.Execute(sel, e.transactions)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Items, res.Total, nil
+}
+
+func (e *engine) auditHead() string {
+	if len(e.auditLogs) == 0 {
+		return ""
+	}
+	return e.auditLogs[len(e.auditLogs)-1].Hash
+}
+
+// sealAuditChain hashes entries written before the chain existed, in log
+// order, and reports whether anything changed. Those entries are only
+// tamper-evident from the moment they are sealed and persisted. Only the
+// leading run of unhashed entries is legacy: an unhashed entry after a
+// sealed one was added or altered outside the application, and is left
+// for VerifyAuditChain to report.
+func (e *engine) sealAuditChain() bool {
+	sealed := false
+	prev := ""
+	for i := range e.auditLogs {
+		l := &e.auditLogs[i]
+		if l.Hash != "" {
+			break
+		}
+		l.Seal(prev)
+		sealed = true
+		prev = l.Hash
+	}
+	return sealed
+}
+
+func (e *engine) findTransaction(id string) (domain.Transaction, bool) {
+	for _, t := range e.transactions {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return domain.Transaction{}, false
+}
+
+func (e *engine) findAuditLog(id string) (domain.AuditLog, bool) {
+	for _, l := range e.auditLogs {
+		if l.ID == id {
+			return l, true
+		}
+	}
+	return domain.AuditLog{}, false
+}
+
+func (e *engine) findUser(id string) (domain.User, bool) {
+	for _, u := range e.users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return domain.User{}, false
+}
+
+func (e *engine) findCategory(id string) (domain.Category, bool) {
+	for _, c := range e.categories {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return domain.Category{}, false
+}
+
+func (e *engine) findUserByUsername(username string) (domain.User, bool) {
+	for _, u := range e.users {
+		if u.Username == username {
+			return u, true
+		}
+	}
+	return domain.User{}, false
+}
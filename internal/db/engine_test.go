@@ -0,0 +1,90 @@
+package db
+
+import (
+	"audit-sendiri/internal/domain"
+	"testing"
+)
+
+func TestSealAuditChainOnlySealsLegacyPrefix(t *testing.T) {
+	e := newEngine()
+	e.auditLogs = []domain.AuditLog{
+		{ID: "legacy-1", Action: "create"},
+		{ID: "legacy-2", Action: "create"},
+	}
+	if !e.sealAuditChain() {
+		t.Fatal("legacy entries were not sealed")
+	}
+	if r := domain.VerifyAuditChain(e.auditLogs); !r.Valid {
+		t.Fatalf("sealed legacy chain is invalid: %s", r.Reason)
+	}
+
+	// An unhashed entry after the sealed ones did not come from the
+	// application and must stay a break in the chain.
+	e.auditLogs = append(e.auditLogs, domain.AuditLog{ID: "forged", Action: "delete"})
+	if e.sealAuditChain() {
+		t.Error("an entry after the sealed chain was sealed")
+	}
+	r := domain.VerifyAuditChain(e.auditLogs)
+	if r.Valid || r.BrokenID != "forged" {
+		t.Errorf("VerifyAuditChain = %+v, want a break at the forged entry", r)
+	}
+
+	// So must an entry whose hash was cleared.
+	e.auditLogs = e.auditLogs[:2]
+	e.auditLogs[1].Hash = ""
+	if e.sealAuditChain() {
+		t.Error("a cleared hash after a sealed entry was repaired")
+	}
+	if r := domain.VerifyAuditChain(e.auditLogs); r.Valid || r.BrokenID != "legacy-2" {
+		t.Errorf("VerifyAuditChain = %+v, want a break at legacy-2", r)
+	}
+}
+
+func TestInsertAuditLogStampsSchemaVersionOnlyWithState(t *testing.T) {
+	m := NewMemoryStore()
+	entries := []domain.AuditLog{
+		{ID: "login", EntityType: "user", Action: "login"},
+		{ID: "create", EntityType: "category", Action: "create", After: domain.AuditState(domain.Category{Name: "Iuran"})},
+		{ID: "delete", EntityType: "category", Action: "delete", Before: domain.AuditState(domain.Category{Name: "Iuran"})},
+	}
+	for _, l := range entries {
+		if err := m.InsertAuditLog(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]int{"login": 0, "create": domain.AuditSchemaVersion, "delete": domain.AuditSchemaVersion}
+	for _, l := range m.AuditLogs() {
+		if l.SchemaVersion != want[l.ID] {
+			t.Errorf("%s: schema version = %d, want %d", l.ID, l.SchemaVersion, want[l.ID])
+		}
+	}
+}
+
+func TestInsertAuditLogChainsEntries(t *testing.T) {
+	m := NewMemoryStore()
+	if err := m.InsertAuditLog(domain.AuditLog{ID: "a1", Action: "login"}); err != nil {
+		t.Fatal(err)
+	}
+	// Entries staged in one update chain to each other, not just to the
+	// committed head.
+	err := m.Update(func(tx domain.Repositories) error {
+		for _, id := range []string{"a2", "a3"} {
+			if err := tx.InsertAuditLog(domain.AuditLog{ID: id, Action: "create"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs := m.AuditLogs()
+	r := domain.VerifyAuditChain(logs)
+	if !r.Valid || r.Checked != 3 || r.Head != logs[2].Hash {
+		t.Fatalf("VerifyAuditChain = %+v", r)
+	}
+	if logs[0].PrevHash != "" || logs[1].PrevHash != logs[0].Hash || logs[2].PrevHash != logs[1].Hash {
+		t.Error("entries are not linked in insertion order")
+	}
+}
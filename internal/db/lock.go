@@ -0,0 +1,34 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const lockFileName = "sawit.lock"
+
+// ErrLocked is returned by Open when another process has the data directory
+// open for writing, usually the server.
+var ErrLocked = errors.New("data directory is in use by another process")
+
+// lockDir takes an exclusive lock on dir, held until the returned file is
+// closed. Only one writer may own a data directory: a second one would fork
+// the audit hash chain, or keep appending to a log the first has already
+// compacted away.
+func lockDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, lockFileName)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, errWouldBlock) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, dir)
+		}
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOpenLocksDataDirectory(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second, err := Open(dir, Options{}); !errors.Is(err, ErrLocked) {
+		if err == nil {
+			second.Close()
+		}
+		t.Fatalf("second writable Open: err = %v, want ErrLocked", err)
+	}
+	reader, err := Open(dir, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("read-only Open while locked: %v", err)
+	}
+	reader.Close()
+
+	first.Close()
+	again, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open after Close: %v", err)
+	}
+	again.Close()
+}
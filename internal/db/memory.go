@@ -0,0 +1,26 @@
+package db
+
+import "audit-sendiri/internal/domain"
+
+var (
+	_ domain.Store = (*SawitDB)(nil)
+	_ domain.Store = (*MemoryStore)(nil)
+)
+
+// MemoryStore is a domain.Store without a disk behind it. It applies the
+// same statements as SawitDB, so handlers behave identically against it; it
+// is meant for tests and tooling.
+type MemoryStore struct {
+	engine
+}
+
+func NewMemoryStore() *MemoryStore {
+	m := &MemoryStore{engine: newEngine()}
+	m.commit = func(statements []string) error {
+		for _, s := range statements {
+			m.applyLocally(s)
+		}
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// On-disk layout of data.sawit:
+//
+//	header: "SAWITLOG" | uint16 format version
+//	record: uint32 payload length | uint32 CRC32C | uint8 record version | payload
+//
+// The checksum covers the record version byte and the payload. Logs written
+// before the header existed are plain int32 length + payload records and are
+// upgraded by compaction on first boot.
+const (
+	fileMagic        = "SAWITLOG"
+	formatVersion    = 2
+	fileHeaderSize   = len(fileMagic) + 2
+	recordVersion    = 1
+	recordHeaderSize = 4 + 4 + 1
+	maxRecordSize    = 64 << 20
+)
+
+var (
+	castagnoli       = crc32.MakeTable(crc32.Castagnoli)
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+func encodeFileHeader() []byte {
+	buf := make([]byte, fileHeaderSize)
+	copy(buf, fileMagic)
+	binary.LittleEndian.PutUint16(buf[len(fileMagic):], formatVersion)
+	return buf
+}
+
+func checkFileHeader(data []byte) error {
+	if len(data) < fileHeaderSize || !bytes.HasPrefix(data, []byte(fileMagic)) {
+		return fmt.Errorf("missing %s header", fileMagic)
+	}
+	if v := binary.LittleEndian.Uint16(data[len(fileMagic):]); v != formatVersion {
+		return fmt.Errorf("unsupported log format version %d", v)
+	}
+	return nil
+}
+
+func encodeRecord(aql string) []byte {
+	buf := make([]byte, recordHeaderSize+len(aql))
+	binary.LittleEndian.PutUint32(buf[0:], uint32(len(aql)))
+	buf[8] = recordVersion
+	copy(buf[recordHeaderSize:], aql)
+	binary.LittleEndian.PutUint32(buf[4:], crc32.Checksum(buf[8:], castagnoli))
+	return buf
+}
+
+// decodeRecord parses the record at the start of data and returns its
+// payload and encoded size. io.ErrUnexpectedEOF means the record runs past
+// the end of data; errCorruptRecord means it is complete but invalid.
+func decodeRecord(data []byte) (string, int, error) {
+	if len(data) < recordHeaderSize {
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	length := binary.LittleEndian.Uint32(data[0:])
+	if length > maxRecordSize {
+		return "", 0, fmt.Errorf("%w: length %d exceeds limit", errCorruptRecord, length)
+	}
+	end := recordHeaderSize + int(length)
+	if len(data) < end {
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	if data[8] != recordVersion {
+		return "", 0, fmt.Errorf("%w: unknown record version %d", errCorruptRecord, data[8])
+	}
+	if crc32.Checksum(data[8:end], castagnoli) != binary.LittleEndian.Uint32(data[4:]) {
+		return "", 0, fmt.Errorf("%w: checksum mismatch", errCorruptRecord)
+	}
+	return string(data[recordHeaderSize:end]), end, nil
+}
+
+func decodeLegacyRecord(data []byte) (string, int, error) {
+	if len(data) < 4 {
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	length := int32(binary.LittleEndian.Uint32(data))
+	if length < 0 || length > maxRecordSize {
+		return "", 0, fmt.Errorf("%w: invalid length %d", errCorruptRecord, length)
+	}
+	end := 4 + int(length)
+	if len(data) < end {
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	return string(data[4:end]), end, nil
+}
+
+// containsValidRecord reports whether any complete, checksummed record can be
+// found in data. A damaged record followed by valid ones is mid-log
+// corruption rather than a torn final write.
+func containsValidRecord(data []byte) bool {
+	for i := 0; i+recordHeaderSize <= len(data); i++ {
+		if data[i+8] != recordVersion {
+			continue
+		}
+		if _, _, err := decodeRecord(data[i:]); err == nil {
+			return true
+		}
+	}
+	return false
+}
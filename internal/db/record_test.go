@@ -0,0 +1,150 @@
+package db
+
+import (
+	"audit-sendiri/internal/domain"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeRecord(t *testing.T) {
+	rec := encodeRecord(`TANAM KE users {"id":"u1"}`)
+	corrupt := func(i int) []byte {
+		b := append([]byte(nil), rec...)
+		b[i] ^= 0xff
+		return b
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "intact", data: rec},
+		{name: "followed by more data", data: append(append([]byte(nil), rec...), rec...)},
+		{name: "empty", data: nil, wantErr: io.ErrUnexpectedEOF},
+		{name: "torn header", data: rec[:recordHeaderSize-1], wantErr: io.ErrUnexpectedEOF},
+		{name: "torn payload", data: rec[:len(rec)-1], wantErr: io.ErrUnexpectedEOF},
+		{name: "flipped payload byte", data: corrupt(len(rec) - 1), wantErr: errCorruptRecord},
+		{name: "flipped checksum", data: corrupt(4), wantErr: errCorruptRecord},
+		{name: "unknown record version", data: corrupt(8), wantErr: errCorruptRecord},
+		{name: "oversized length", data: corrupt(3), wantErr: errCorruptRecord},
+	}
+	for _, tt := range tests {
+		stmt, n, err := decodeRecord(tt.data)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || stmt != `TANAM KE users {"id":"u1"}` || n != len(rec) {
+			t.Errorf("%s: decodeRecord = %q, %d, %v", tt.name, stmt, n, err)
+		}
+	}
+}
+
+func TestCheckFileHeader(t *testing.T) {
+	future := encodeFileHeader()
+	future[len(fileMagic)] = formatVersion + 1
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "current", data: encodeFileHeader()},
+		{name: "short", data: []byte(fileMagic), wantErr: true},
+		{name: "wrong magic", data: []byte("SAWITLOX\x02\x00"), wantErr: true},
+		{name: "newer version", data: future, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := checkFileHeader(tt.data); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// writeLog opens a fresh store in a temporary directory, inserts n
+// transactions and returns the directory and the log contents.
+func writeLog(t *testing.T, n int) (string, []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		tx := domain.Transaction{ID: string(rune('a' + i)), Type: "income", Amount: domain.Rupiah(1000)}
+		if err := store.InsertTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+	data, err := os.ReadFile(logPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, data
+}
+
+func TestOpenRecoversTornTail(t *testing.T) {
+	tail := encodeRecord(`TANAM KE transactions {"id":"torn"}`)
+	tests := []struct {
+		name string
+		tail []byte
+	}{
+		{name: "torn header", tail: tail[:recordHeaderSize-2]},
+		{name: "torn payload", tail: tail[:len(tail)-3]},
+		{name: "garbage", tail: []byte{0xde, 0xad, 0xbe, 0xef, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}},
+	}
+	for _, tt := range tests {
+		dir, data := writeLog(t, 2)
+		if err := os.WriteFile(logPath(dir), append(append([]byte(nil), data...), tt.tail...), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		store, err := Open(dir, Options{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := len(store.Transactions()); got != 2 {
+			t.Errorf("%s: %d transactions after recovery, want 2", tt.name, got)
+		}
+		store.Close()
+		if after, _ := os.ReadFile(logPath(dir)); len(after) != len(data) {
+			t.Errorf("%s: log is %d bytes after recovery, want it truncated to %d", tt.name, len(after), len(data))
+		}
+	}
+}
+
+func TestOpenRefusesMidLogCorruption(t *testing.T) {
+	dir, data := writeLog(t, 3)
+	// Damage the payload of the first record; the two after it are intact.
+	data[fileHeaderSize+recordHeaderSize] ^= 0xff
+	if err := os.WriteFile(logPath(dir), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir, Options{}); err == nil {
+		t.Fatal("opened a log with a corrupt record before intact ones")
+	}
+	if store, err := Open(dir, Options{ReadOnly: true}); err == nil {
+		store.Close()
+		t.Error("read-only open skipped mid-log corruption")
+	}
+
+	store, err := Open(dir, Options{Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if got := len(store.Transactions()); got != 0 {
+		t.Errorf("%d transactions after repair, want 0", got)
+	}
+	backups, _ := filepath.Glob(logPath(dir) + ".corrupt-*")
+	if len(backups) != 1 {
+		t.Errorf("backups = %v, want one", backups)
+	}
+}
@@ -1,270 +1,434 @@
 package db
 
 import (
+	"audit-sendiri/internal/db/aql"
 	"audit-sendiri/internal/domain"
-	"crypto/rand"
-	"encoding/binary"
-	"encoding/hex"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
+const (
+	logFileName          = "data.sawit"
+	DefaultSnapshotEvery = 1000
+)
+
+var errReadOnly = errors.New("database is opened read-only")
+
+// SawitDB is the engine persisted to an append-only AQL log. Readers get
+// copies through the repository methods; writers go through ExecuteAQL,
+// Batch or Update.
 type SawitDB struct {
-	Path        string
-	file        *os.File
-	mu          sync.Mutex
-	Transactions []domain.Transaction
-	AuditLogs    []domain.AuditLog
-	Users        []domain.User
-	Settings     domain.AppSettings
+	engine
+
+	Path string
+	file *os.File
+	// lock holds the data directory for this process; nil when read-only.
+	lock *os.File
+
+	SnapshotEvery int
+
+	epoch    uint64
+	appended int
+	legacy   bool
+	repair   bool
+	readOnly bool
+}
+
+type Options struct {
+	// SnapshotEvery is the number of appended records after which the log is
+	// compacted into a snapshot. Zero disables automatic compaction.
+	SnapshotEvery int
+	// Repair allows startup to truncate the log at a corrupt record even when
+	// intact records follow it. The original log is backed up first.
+	Repair bool
+	// ReadOnly opens the log without ever writing to it, for tools that
+	// inspect a data directory a running server may own. Damaged tails are
+	// skipped instead of truncated and every write fails. Only writable
+	// opens lock the directory; Open fails with ErrLocked if it is taken.
+	ReadOnly bool
 }
 
-func generateID() string {
-	bytes := make([]byte, 8)
-	if _, err := rand.Read(bytes); err != nil {
-		return fmt.Sprintf("%d", time.Now().UnixNano())
+// DataDir is the data directory the server and auditctl use: DB_PATH, or
+// ./data when it is not set.
+func DataDir() string {
+	if dir := os.Getenv("DB_PATH"); dir != "" {
+		return dir
 	}
-	return hex.EncodeToString(bytes)
+	return "./data"
 }
 
 func NewSawitDB(path string) (*SawitDB, error) {
-	if err := os.MkdirAll(path, 0755); err != nil {
-		return nil, err
-	}
+	return Open(path, Options{SnapshotEvery: DefaultSnapshotEvery})
+}
 
-	filePath := path + "/data.sawit"
-	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+func Open(path string, opts Options) (*SawitDB, error) {
+	var f, lock *os.File
+	var err error
+	if opts.ReadOnly {
+		f, err = os.Open(logPath(path))
+	} else {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+		if lock, err = lockDir(path); err != nil {
+			return nil, err
+		}
+		f, err = os.OpenFile(logPath(path), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			lock.Close()
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	db := &SawitDB{
-		Path: path,
-		file: f,
-		Transactions: []domain.Transaction{},
-		AuditLogs:    []domain.AuditLog{},
-		Users:        []domain.User{},
-		Settings:     domain.AppSettings{RTName: "001", RWName: "001"},
+		engine:        newEngine(),
+		Path:          path,
+		file:          f,
+		lock:          lock,
+		SnapshotEvery: opts.SnapshotEvery,
+		repair:        opts.Repair && !opts.ReadOnly,
+		readOnly:      opts.ReadOnly,
 	}
+	db.commit = db.commitLocked
 
 	if err := db.Rehydrate(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
+	if db.readOnly {
+		return db, nil
+	}
+
+	if db.legacy {
+		if err := db.Compact(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("upgrading legacy log: %w", err)
+		}
+		db.legacy = false
+	} else if db.SnapshotEvery > 0 && db.appended >= db.SnapshotEvery {
+		if err := db.Compact(); err != nil {
+			log.Printf("Startup compaction failed: %v", err)
+		}
+	}
+
+	db.mu.Lock()
+	sealed := db.sealAuditChain()
+	db.mu.Unlock()
+	if sealed {
+		log.Println("Sealed audit log entries written before hash chaining.")
+		if err := db.Compact(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("persisting sealed audit log: %w", err)
+		}
+	}
+
 	return db, nil
 }
 
+func logPath(dir string) string {
+	return dir + "/" + logFileName
+}
+
 func (db *SawitDB) Rehydrate() error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
 	log.Println("Rehydrating database from disk...")
-	
-	db.file.Seek(0, 0)
-	
-	for {
-		var length int32
-		err := binary.Read(db.file, binary.LittleEndian, &length)
-		if err == io.EOF {
+
+	snap, err := readSnapshot(db.Path)
+	if err != nil {
+		return err
+	}
+	var snapEpoch uint64
+	if snap != nil {
+		db.loadSnapshot(snap)
+		snapEpoch = snap.Epoch
+	}
+
+	if _, err := db.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(db.file)
+	if err != nil {
+		return err
+	}
+
+	decode := decodeRecord
+	pos := 0
+	switch {
+	case len(data) < fileHeaderSize && bytes.HasPrefix([]byte(fileMagic), data):
+		// Empty log, or the header itself was torn on first boot.
+		if db.readOnly {
 			break
 		}
-		if err != nil {
+		if err := db.truncateLog(0); err != nil {
 			return err
 		}
-
-		aqlBytes := make([]byte, length)
-		_, err = db.file.Read(aqlBytes)
-		if err != nil {
+		if _, err := db.file.Write(encodeFileHeader()); err != nil {
 			return err
 		}
-
-		aql := string(aqlBytes)
-		db.applyLocally(aql)
+		data = nil
+	case bytes.HasPrefix(data, []byte(fileMagic)):
+		if err := checkFileHeader(data); err != nil {
+			return err
+		}
+		pos = fileHeaderSize
+	default:
+		log.Println("Legacy log format detected; it will be upgraded after rehydration.")
+		decode = decodeLegacyRecord
+		db.legacy = true
 	}
 
-	db.file.Seek(0, 2)
-	log.Printf("Rehydration complete. %d transactions, %d users loaded.", len(db.Transactions), len(db.Users))
-	return nil
-}
+	logEpoch := uint64(0)
+	first := true
+	replayFrom := 0
+	db.appended = 0
 
-func (db *SawitDB) applyLocally(aql string) {
-	if strings.HasPrefix(aql, "TANAM JSON") || strings.HasPrefix(aql, "UBAH JSON") {
-		op := "TANAM"
-		if strings.HasPrefix(aql, "UBAH JSON") {
-			op = "UBAH"
+	for pos < len(data) {
+		stmt, n, err := decode(data[pos:])
+		if err != nil {
+			if err := db.recoverTail(data, pos, err); err != nil {
+				return err
+			}
+			break
 		}
+		start := pos
+		pos += n
 
-		parts := strings.SplitN(aql, " ", 4)
-		if len(parts) < 4 {
-			return
-		}
-		tableName := parts[2]
-		payload := parts[3]
-
-		switch tableName {
-		case "transactions":
-			var tx domain.Transaction
-			if err := json.Unmarshal([]byte(payload), &tx); err == nil {
-				if op == "TANAM" {
-					db.Transactions = append(db.Transactions, tx)
-				} else {
-					for i, t := range db.Transactions {
-						if t.ID == tx.ID {
-							db.Transactions[i] = tx
-							break
-						}
-					}
-				}
-			}
-		case "users":
-			var user domain.User
-			if err := json.Unmarshal([]byte(payload), &user); err == nil {
-				if op == "TANAM" {
-					db.Users = append(db.Users, user)
-				} else {
-					for i, u := range db.Users {
-						if u.ID == user.ID {
-							db.Users[i] = user
-							break
-						}
-					}
-				}
-			} else {
-				log.Printf("Failed to unmarshal user: %v", err)
-			}
-		case "audit_log":
-			var auditLog domain.AuditLog
-			if err := json.Unmarshal([]byte(payload), &auditLog); err == nil {
-				if auditLog.ID == "" {
-					auditLog.ID = generateID()
-				}
-				if op == "TANAM" {
-					db.AuditLogs = append(db.AuditLogs, auditLog)
-				}
+		if first {
+			first = false
+			if epoch, ok := parseEpochMarker(stmt); ok {
+				logEpoch = epoch
 			}
-		case "settings":
-			var s domain.AppSettings
-			if err := json.Unmarshal([]byte(payload), &s); err == nil {
-				db.Settings = s
+			switch {
+			case logEpoch == snapEpoch:
+			case snap != nil && logEpoch+1 == snapEpoch:
+				// The snapshot was written but the log rewrite did not finish;
+				// everything before the recorded offset is already in the snapshot.
+				replayFrom = int(snap.LogOffset)
+			default:
+				return fmt.Errorf("snapshot epoch %d does not match log epoch %d", snapEpoch, logEpoch)
 			}
 		}
-		return
+		if start < replayFrom {
+			continue
+		}
+		if _, ok := parseEpochMarker(stmt); ok {
+			continue
+		}
+
+		db.applyLocally(stmt)
+		db.appended++
 	}
+	db.epoch = snapEpoch
 
-	if strings.HasPrefix(aql, "HAPUS JSON") {
-		parts := strings.SplitN(aql, " ", 4)
-		if len(parts) < 4 {
-			return
+	if _, err := db.file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	if first && snapEpoch > 0 && !db.legacy && !db.readOnly {
+		if _, err := db.file.Write(encodeRecord(epochMarker(snapEpoch))); err != nil {
+			return err
 		}
-		tableName := parts[2]
-		idRaw := parts[3] 
-		type IDWrapper struct {
-			ID string `json:"id"`
+	}
+	log.Printf("Rehydration complete. %d transactions, %d users loaded (%d log records replayed).", len(db.transactions), len(db.users), db.appended)
+	return nil
+}
+
+// recoverTail handles a record at pos that could not be decoded. A torn final
+// write is truncated with a warning; damage followed by intact records is
+// only truncated in repair mode, after the original log has been backed up.
+func (db *SawitDB) recoverTail(data []byte, pos int, cause error) error {
+	torn := errors.Is(cause, io.ErrUnexpectedEOF)
+	if !db.legacy {
+		torn = !containsValidRecord(data[pos+1:])
+	}
+
+	if !torn {
+		if !db.repair {
+			return fmt.Errorf("corrupt record at offset %d of %s followed by intact records (%v); start in repair mode to truncate the log at this point", pos, logFileName, cause)
 		}
-		var idObj IDWrapper
-		if err := json.Unmarshal([]byte(idRaw), &idObj); err != nil {
-			return
+		backup := fmt.Sprintf("%s.corrupt-%d", logPath(db.Path), time.Now().Unix())
+		if err := os.WriteFile(backup, data, 0644); err != nil {
+			return fmt.Errorf("backing up corrupt log: %w", err)
 		}
+		log.Printf("WARNING: repair mode: discarding %d bytes from offset %d of %s (%v). Original saved to %s.", len(data)-pos, pos, logFileName, cause, backup)
+	} else {
+		log.Printf("WARNING: discarding torn record (%d bytes) at offset %d of %s: %v", len(data)-pos, pos, logFileName, cause)
+	}
+	if db.readOnly {
+		return nil
+	}
+	return db.truncateLog(int64(pos))
+}
 
-		switch tableName {
-		case "users":
-			newUsers := []domain.User{}
-			for _, u := range db.Users {
-				if u.ID != idObj.ID {
-					newUsers = append(newUsers, u)
-				}
-			}
-			db.Users = newUsers
-		}
-		return
+func (db *SawitDB) truncateLog(size int64) error {
+	if err := db.file.Truncate(size); err != nil {
+		return err
 	}
+	return db.file.Sync()
 }
 
-func (db *SawitDB) ExecuteAQL(query string) (interface{}, error) {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	aqlBytes := []byte(query)
-	length := int32(len(aqlBytes))
+func epochMarker(epoch uint64) string {
+	return "BIBIT " + strconv.FormatUint(epoch, 10)
+}
 
-	if err := binary.Write(db.file, binary.LittleEndian, length); err != nil {
-		return nil, err
+func parseEpochMarker(aql string) (uint64, bool) {
+	if !strings.HasPrefix(aql, "BIBIT ") {
+		return 0, false
 	}
-	if _, err := db.file.Write(aqlBytes); err != nil {
-		return nil, err
+	epoch, err := strconv.ParseUint(strings.TrimPrefix(aql, "BIBIT "), 10, 64)
+	if err != nil {
+		return 0, false
 	}
-	if err := db.file.Sync(); err != nil {
-		return nil, err
+	return epoch, true
+}
+
+// ExecuteAQL runs a single AQL statement. PANEN queries are answered from
+// memory and never reach the log; every other statement is appended and
+// synced before it is applied.
+func (db *SawitDB) ExecuteAQL(query string) (interface{}, error) {
+	if aql.IsQuery(query) {
+		return db.query(query)
 	}
-	db.applyLocally(query)
-	upper := strings.ToUpper(query)
-	if strings.Contains(upper, "PANEN * DARI JSON TRANSACTIONS") || strings.Contains(upper, "PANEN * DARI TRANSACTIONS") {
-		return db.Transactions, nil
-	} else if strings.Contains(upper, "PANEN * DARI AUDIT_LOG") {
-		return db.AuditLogs, nil
-	} else if strings.Contains(upper, "PANEN * DARI USERS") {
-		return db.Users, nil
+	if db.readOnly {
+		return nil, errReadOnly
 	}
 
-	return nil, nil
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return nil, db.executeLocked(query)
 }
 
-func (db *SawitDB) InsertTransaction(tx domain.Transaction) {
-	payload, _ := json.Marshal(tx)
-	query := fmt.Sprintf("TANAM JSON transactions %s", string(payload))
-	db.ExecuteAQL(query)
+func (db *SawitDB) commitLocked(statements []string) error {
+	if db.readOnly && len(statements) > 0 {
+		return errReadOnly
+	}
+	switch len(statements) {
+	case 0:
+		return nil
+	case 1:
+		return db.executeLocked(statements[0])
+	}
+
+	payload, err := json.Marshal(statements)
+	if err != nil {
+		return err
+	}
+	return db.executeLocked(batchPrefix + string(payload))
 }
 
-func (db *SawitDB) UpdateTransaction(tx domain.Transaction) {
-	payload, _ := json.Marshal(tx)
-	query := fmt.Sprintf("UBAH JSON transactions %s", string(payload))
-	db.ExecuteAQL(query)
+func (db *SawitDB) executeLocked(query string) error {
+	if err := db.appendLocked(query); err != nil {
+		return err
+	}
+	db.applyLocally(query)
+	db.appended++
+	if db.SnapshotEvery > 0 && db.appended >= db.SnapshotEvery {
+		if err := db.compactLocked(); err != nil {
+			log.Printf("Compaction failed: %v", err)
+		}
+	}
+	return nil
 }
 
-func (db *SawitDB) InsertUser(u domain.User) {
-	payload, _ := json.Marshal(u)
-	query := fmt.Sprintf("TANAM JSON users %s", string(payload))
-	db.ExecuteAQL(query)
+// appendLocked writes and syncs one record. On failure the log is cut back
+// to where it was, so a half-written record never precedes later appends.
+func (db *SawitDB) appendLocked(query string) error {
+	offset, err := db.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := db.file.Write(encodeRecord(query)); err != nil {
+		db.rollback(offset)
+		return fmt.Errorf("writing log record: %w", err)
+	}
+	if err := db.file.Sync(); err != nil {
+		db.rollback(offset)
+		return fmt.Errorf("syncing log: %w", err)
+	}
+	return nil
 }
 
-func (db *SawitDB) InsertAuditLog(log domain.AuditLog) {
-	if log.ID == "" {
-		log.ID = generateID()
+func (db *SawitDB) rollback(offset int64) {
+	if err := db.truncateLog(offset); err != nil {
+		log.Printf("WARNING: failed to roll back log to offset %d: %v", offset, err)
 	}
-	payload, _ := json.Marshal(log)
-	query := fmt.Sprintf("TANAM JSON audit_log %s", string(payload))
-	db.ExecuteAQL(query)
 }
 
-func (db *SawitDB) UpdateUser(u domain.User) {
-	payload, _ := json.Marshal(u)
-	query := fmt.Sprintf("UBAH JSON users %s", string(payload))
-	db.ExecuteAQL(query)
+func (db *SawitDB) query(query string) (interface{}, error) {
+	sel, err := aql.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	switch sel.Table {
+	case "transactions":
+		return resultValue(aql.Execute(sel, db.transactions))
+	case "audit_log":
+		return resultValue(aql.Execute(sel, db.auditLogs))
+	case "users":
+		return resultValue(aql.Execute(sel, db.users))
+	case "categories":
+		return resultValue(aql.Execute(sel, db.categories))
+	}
+	return nil, fmt.Errorf("aql: unknown table %q", sel.Table)
 }
 
-func (db *SawitDB) DeleteUser(id string) {
-	payload := fmt.Sprintf(`{"id":"%s"}`, id)
-	query := fmt.Sprintf("HAPUS JSON users %s", payload)
-	db.ExecuteAQL(query)
+func resultValue[T any](res aql.Result[T], err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	if res.Rows != nil {
+		return res.Rows, nil
+	}
+	return res.Items, nil
 }
 
-func (db *SawitDB) Migrate() {
-	queries := []string{
-		"LAHAN users",
-		"LAHAN categories",
-		"LAHAN transactions",
-		"LAHAN audit_log",
+func (db *SawitDB) Migrate() error {
+	tables := []string{
+		"users",
+		"categories",
+		"transactions",
+		"audit_log",
+		"closed_periods",
 	}
-	for _, q := range queries {
-		db.ExecuteAQL(q)
+	for _, t := range tables {
+		db.mu.RLock()
+		exists := db.tables[t]
+		db.mu.RUnlock()
+		if !exists {
+			if _, err := db.ExecuteAQL("LAHAN " + t); err != nil {
+				return err
+			}
+		}
 	}
+
+	err := db.Update(func(t domain.Repositories) error {
+		return migrateCategories(t, time.Now())
+	})
+	if err != nil {
+		return fmt.Errorf("migrating categories: %w", err)
+	}
+	return nil
 }
 
 func (db *SawitDB) Close() {
 	db.file.Close()
+	if db.lock != nil {
+		db.lock.Close()
+	}
 }
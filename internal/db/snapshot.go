@@ -0,0 +1,192 @@
+package db
+
+import (
+	"audit-sendiri/internal/domain"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const snapshotFileName = "data.snapshot"
+
+// snapshot is the full in-memory state at the moment of compaction. Epoch
+// identifies the log generation that continues after it; LogOffset is the
+// position in the previous generation's log up to which the snapshot is
+// complete, used when a crash interrupted the log rewrite.
+type snapshot struct {
+	Epoch        uint64             `json:"epoch"`
+	LogOffset    int64              `json:"log_offset"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Tables       []string           `json:"tables"`
+	Transactions transactionList    `json:"transactions"`
+	AuditLogs    []domain.AuditLog  `json:"audit_logs"`
+	Users        []domain.User      `json:"users"`
+	Categories   []domain.Category  `json:"categories"`
+	Settings     domain.AppSettings `json:"settings"`
+
+	ClosedPeriods []domain.ClosedPeriod `json:"closed_periods,omitempty"`
+}
+
+func snapshotPath(dir string) string {
+	return filepath.Join(dir, snapshotFileName)
+}
+
+func readSnapshot(dir string) (*snapshot, error) {
+	f, err := os.Open(snapshotPath(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snap snapshot
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+func (db *SawitDB) loadSnapshot(snap *snapshot) {
+	db.transactions = append([]domain.Transaction{}, snap.Transactions...)
+	db.auditLogs = append([]domain.AuditLog{}, snap.AuditLogs...)
+	db.users = append([]domain.User{}, snap.Users...)
+	db.categories = append([]domain.Category{}, snap.Categories...)
+	db.closedPeriods = append([]domain.ClosedPeriod{}, snap.ClosedPeriods...)
+	db.settings = snap.Settings
+	db.revision++
+	db.tables = map[string]bool{}
+	for _, t := range snap.Tables {
+		db.tables[t] = true
+	}
+}
+
+// Compact writes the current state to a snapshot and truncates the log down
+// to the records that follow it.
+func (db *SawitDB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.compactLocked()
+}
+
+func (db *SawitDB) compactLocked() error {
+	if db.readOnly {
+		return errReadOnly
+	}
+	offset, err := db.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	tables := make([]string, 0, len(db.tables))
+	for t := range db.tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+
+	snap := snapshot{
+		Epoch:        db.epoch + 1,
+		LogOffset:    offset,
+		CreatedAt:    time.Now(),
+		Tables:       tables,
+		Transactions: db.transactions,
+		AuditLogs:    db.auditLogs,
+		Users:        db.users,
+		Categories:   db.categories,
+		Settings:     db.settings,
+
+		ClosedPeriods: db.closedPeriods,
+	}
+
+	err = writeFileAtomic(snapshotPath(db.Path), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(snap)
+	})
+	if err != nil {
+		return err
+	}
+
+	// From here on the snapshot is authoritative. If the process dies before
+	// the rename below, Rehydrate replays the old log from snap.LogOffset.
+	db.file.Close()
+	err = writeFileAtomic(logPath(db.Path), func(w io.Writer) error {
+		if _, err := w.Write(encodeFileHeader()); err != nil {
+			return err
+		}
+		_, err := w.Write(encodeRecord(epochMarker(snap.Epoch)))
+		return err
+	})
+	if rerr := db.reopen(); rerr != nil {
+		return rerr
+	}
+	if err != nil {
+		return err
+	}
+
+	db.epoch = snap.Epoch
+	db.appended = 0
+	log.Printf("Compacted log into snapshot epoch %d (%d bytes of log reclaimed).", snap.Epoch, offset)
+	return nil
+}
+
+func (db *SawitDB) reopen() error {
+	f, err := os.OpenFile(logPath(db.Path), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	db.file = f
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file, syncs it and renames it over
+// path so readers only ever see the old or the new content.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	bw := bufio.NewWriter(tmp)
+	if err := write(bw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes a directory entry after a rename. Not every platform
+// supports syncing directories, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"audit-sendiri/internal/domain"
+	"sync"
+)
+
+// maxCachedSummaries bounds the cache between writes; distinct ranges beyond
+// it start a fresh cache.
+const maxCachedSummaries = 64
+
+// summaryCache keeps computed summaries for one engine revision. Any applied
+// statement bumps the revision, which empties the cache on the next lookup.
+type summaryCache struct {
+	mu       sync.Mutex
+	revision uint64
+	entries  map[string]domain.Summary
+}
+
+func (c *summaryCache) get(revision uint64, key string, compute func() domain.Summary) domain.Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.revision != revision || c.entries == nil || len(c.entries) >= maxCachedSummaries {
+		c.revision = revision
+		c.entries = map[string]domain.Summary{}
+	}
+	s, ok := c.entries[key]
+	if !ok {
+		s = compute()
+		c.entries[key] = s
+	}
+	return copySummary(s)
+}
+
+func copySummary(s domain.Summary) domain.Summary {
+	s.Categories = append([]domain.CategorySummary{}, s.Categories...)
+	s.Series = append([]domain.SummaryBucket{}, s.Series...)
+	return s
+}
+
+// Summary aggregates the transactions matching q. Results are cached until
+// the next write.
+func (e *engine) Summary(q domain.SummaryQuery) domain.Summary {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.summary(q)
+}
+
+func (e *engine) summary(q domain.SummaryQuery) domain.Summary {
+	return e.summaries.get(e.revision, q.Key(), func() domain.Summary {
+		return domain.Summarize(e.transactions, e.categories, q)
+	})
+}
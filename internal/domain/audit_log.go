@@ -1,6 +1,14 @@
 package domain
 
-import "time"
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
 
 type AuditLog struct {
 	ID         string    `json:"id"`
@@ -11,4 +19,183 @@ type AuditLog struct {
 	Details    string    `json:"details,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 	CreatedBy  string    `json:"created_by"`
+	// PrevHash and Hash chain every entry to the one before it. Fields added
+	// after these must be omitempty so older entries keep their hashes.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+
+	CreatedByUsername string `json:"created_by_username,omitempty"`
+	IPAddress         string `json:"ip_address,omitempty"`
+	UserAgent         string `json:"user_agent,omitempty"`
+
+	// Before and After hold the entity as it was and as it became. They
+	// must never contain secrets such as password hashes.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+	// SchemaVersion is AuditSchemaVersion for entries written with Before
+	// and After; older entries leave it zero and may carry Details instead.
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	// ImportJob is set on every entry written by a bulk import.
+	ImportJob string `json:"import_job,omitempty"`
+}
+
+const AuditSchemaVersion = 2
+
+// FieldChange is one top-level field that differs between Before and After.
+type FieldChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Changes lists the fields the entry changed. Entries written before
+// schema version 2 are converted from their "old -> new" Details strings;
+// a value that contains the arrow itself cannot be split reliably and is
+// reported as an error.
+func (l AuditLog) Changes() (map[string]FieldChange, error) {
+	if l.SchemaVersion >= AuditSchemaVersion || l.Before != nil || l.After != nil {
+		return diffStates(l.Before, l.After)
+	}
+	if l.Details == "" {
+		return nil, nil
+	}
+
+	var legacy map[string]string
+	if err := json.Unmarshal([]byte(l.Details), &legacy); err != nil {
+		return nil, fmt.Errorf("unreadable audit details: %w", err)
+	}
+	changes := make(map[string]FieldChange, len(legacy))
+	for field, change := range legacy {
+		parts := strings.Split(change, legacyArrow)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("ambiguous legacy change for %s: %q", field, change)
+		}
+		before, after := legacyValue(field, parts[0]), legacyValue(field, parts[1])
+		if before == nil || after == nil {
+			return nil, fmt.Errorf("unreadable legacy value for %s: %q", field, change)
+		}
+		changes[field] = FieldChange{Before: before, After: after}
+	}
+	return changes, nil
+}
+
+const legacyArrow = " -> "
+
+func legacyValue(field, s string) json.RawMessage {
+	if field == "amount" {
+		m, err := ParseMoney(s)
+		if err != nil {
+			return nil
+		}
+		return AuditState(m)
+	}
+	return AuditState(s)
+}
+
+func diffStates(before, after json.RawMessage) (map[string]FieldChange, error) {
+	var b, a map[string]json.RawMessage
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &a); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := map[string]FieldChange{}
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !bytes.Equal(bv, av) {
+			changes[k] = FieldChange{Before: bv, After: a[k]}
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = FieldChange{After: av}
+		}
+	}
+	return changes, nil
+}
+
+// AuditState encodes v for the Before and After fields of an entry.
+func AuditState(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Actor is whoever caused a change: the authenticated user, if any, and
+// the client the request came from.
+type Actor struct {
+	UserID    string
+	Username  string
+	IPAddress string
+	UserAgent string
+}
+
+// Stamp records a as the author of the entry. It must be called before the
+// entry is sealed.
+func (l *AuditLog) Stamp(a Actor) {
+	if l.CreatedBy == "" {
+		l.CreatedBy = a.UserID
+		l.CreatedByUsername = a.Username
+	}
+	l.IPAddress = a.IPAddress
+	l.UserAgent = a.UserAgent
+}
+
+// ComputeHash returns the SHA-256 of the entry's canonical JSON: every field,
+// including PrevHash, except Hash itself.
+func (l AuditLog) ComputeHash() string {
+	l.Hash = ""
+	payload, err := json.Marshal(l)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Seal links the entry to prevHash and stores its own hash.
+func (l *AuditLog) Seal(prevHash string) {
+	l.PrevHash = prevHash
+	l.Hash = l.ComputeHash()
+}
+
+type ChainReport struct {
+	Valid   bool   `json:"valid"`
+	Checked int    `json:"checked"`
+	Head    string `json:"head,omitempty"`
+	// The first broken link, when Valid is false.
+	BrokenIndex *int   `json:"broken_index,omitempty"`
+	BrokenID    string `json:"broken_id,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// VerifyAuditChain walks logs in order and reports the first entry whose
+// hash does not match its content or whose PrevHash does not match the
+// entry before it.
+func VerifyAuditChain(logs []AuditLog) ChainReport {
+	prev := ""
+	for i, l := range logs {
+		var reason string
+		switch {
+		case l.Hash == "":
+			reason = "entry is not sealed"
+		case l.PrevHash != prev:
+			reason = fmt.Sprintf("prev_hash %q does not match previous entry hash %q", l.PrevHash, prev)
+		case l.ComputeHash() != l.Hash:
+			reason = "content does not match its hash"
+		}
+		if reason != "" {
+			idx := i
+			return ChainReport{Checked: i, Head: prev, BrokenIndex: &idx, BrokenID: l.ID, Reason: reason}
+		}
+		prev = l.Hash
+	}
+	return ChainReport{Valid: true, Checked: len(logs), Head: prev}
 }
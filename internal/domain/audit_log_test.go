@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func sealedChain() []AuditLog {
+	logs := []AuditLog{
+		{ID: "a1", EntityType: "transaction", EntityID: "t1", Action: "create", Note: "Amount: Rp 50.000"},
+		{ID: "a2", EntityType: "transaction", EntityID: "t1", Action: "update", Note: "amount: Rp 50.000 -> Rp 75.000"},
+		{ID: "a3", EntityType: "transaction", EntityID: "t1", Action: "delete"},
+	}
+	prev := ""
+	for i := range logs {
+		logs[i].Seal(prev)
+		prev = logs[i].Hash
+	}
+	return logs
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func([]AuditLog) []AuditLog
+		wantBroken string
+		wantReason string
+	}{
+		{name: "intact", tamper: func(l []AuditLog) []AuditLog { return l }},
+		{name: "empty", tamper: func([]AuditLog) []AuditLog { return nil }},
+		{
+			name:       "edited note",
+			tamper:     func(l []AuditLog) []AuditLog { l[1].Note = "amount: Rp 50.000 -> Rp 5.000"; return l },
+			wantBroken: "a2",
+			wantReason: "content does not match",
+		},
+		{
+			name: "edited and resealed",
+			tamper: func(l []AuditLog) []AuditLog {
+				l[1].Note = "amount: Rp 50.000 -> Rp 5.000"
+				l[1].Seal(l[0].Hash)
+				return l
+			},
+			wantBroken: "a3",
+			wantReason: "prev_hash",
+		},
+		{
+			name:       "removed entry",
+			tamper:     func(l []AuditLog) []AuditLog { return append(l[:1], l[2]) },
+			wantBroken: "a3",
+			wantReason: "prev_hash",
+		},
+		{
+			name:       "cleared hash",
+			tamper:     func(l []AuditLog) []AuditLog { l[0].Hash = ""; return l },
+			wantBroken: "a1",
+			wantReason: "not sealed",
+		},
+		{
+			name:       "appended unsealed entry",
+			tamper:     func(l []AuditLog) []AuditLog { return append(l, AuditLog{ID: "a4", Action: "delete"}) },
+			wantBroken: "a4",
+			wantReason: "not sealed",
+		},
+	}
+	for _, tt := range tests {
+		logs := tt.tamper(sealedChain())
+		r := VerifyAuditChain(logs)
+		if tt.wantBroken == "" {
+			if !r.Valid || r.Checked != len(logs) {
+				t.Errorf("%s: %+v, want a valid chain of %d", tt.name, r, len(logs))
+			}
+			continue
+		}
+		if r.Valid || r.BrokenID != tt.wantBroken || !strings.Contains(r.Reason, tt.wantReason) {
+			t.Errorf("%s: %+v, want a break at %s (%s)", tt.name, r, tt.wantBroken, tt.wantReason)
+		}
+	}
+
+	logs := sealedChain()
+	if r := VerifyAuditChain(logs); r.Head != logs[2].Hash {
+		t.Errorf("head = %q, want %q", r.Head, logs[2].Hash)
+	}
+}
+
+func TestAuditLogChanges(t *testing.T) {
+	type state struct {
+		Amount Money  `json:"amount"`
+		Note   string `json:"note"`
+	}
+	tests := []struct {
+		name    string
+		log     AuditLog
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "states",
+			log:  AuditLog{Before: AuditState(state{Rupiah(50000), "a"}), After: AuditState(state{Rupiah(75000), "a"})},
+			want: map[string]string{"amount": "50000 -> 75000"},
+		},
+		{
+			name: "created",
+			log:  AuditLog{After: AuditState(state{Rupiah(1), "a"})},
+			want: map[string]string{"amount": " -> 1", "note": ` -> "a"`},
+		},
+		{
+			name: "legacy details",
+			log:  AuditLog{Details: `{"amount":"Rp 50.000 -> Rp 75.000","description":"a -> b"}`},
+			want: map[string]string{"amount": "50000 -> 75000", "description": `"a" -> "b"`},
+		},
+		{name: "no details", log: AuditLog{Action: "login"}},
+		{
+			name:    "ambiguous legacy arrow",
+			log:     AuditLog{Details: `{"description":"a -> b -> c"}`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		changes, err := tt.log.Changes()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: Changes() = %v, want error", tt.name, changes)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := map[string]string{}
+		for field, c := range changes {
+			got[field] = string(c.Before) + " -> " + string(c.After)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: changes = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for field, want := range tt.want {
+			if got[field] != want {
+				t.Errorf("%s: %s = %s, want %s", tt.name, field, got[field], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Category is master data for Transaction.Category. Kind matches the
+// transaction type it may be used with.
+type Category struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"` // income | expense
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrCategoryInactive    = errors.New("category is inactive")
+	ErrCategoryKindInvalid = errors.New("category kind does not match the transaction type")
+)
+
+// CategoryKey folds case and whitespace so "Iuran  Warga" and "iuran warga"
+// name the same category.
+func CategoryKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// NormalizeCategoryName trims and collapses whitespace, keeping case.
+func NormalizeCategoryName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// CategoryCode derives an upper-case code such as "IURAN-WARGA" from a name.
+func CategoryCode(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToUpper(name) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// UniqueCategoryCode returns code, or code with a numeric suffix, such that
+// no category in categories uses it.
+func UniqueCategoryCode(categories []Category, code string) string {
+	if code == "" {
+		code = "KATEGORI"
+	}
+	candidate := code
+	for n := 2; ; n++ {
+		taken := false
+		for _, c := range categories {
+			if c.Code == candidate {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", code, n)
+	}
+}
+
+// ValidateCategory checks c against the existing categories, ignoring the
+// entry with c's own ID so updates can keep their code and name.
+func ValidateCategory(c Category, existing []Category) error {
+	if c.Kind != "income" && c.Kind != "expense" {
+		return errors.New("kind must be income or expense")
+	}
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Code == "" {
+		return errors.New("code is required")
+	}
+
+	for _, other := range existing {
+		if other.ID == c.ID {
+			continue
+		}
+		if other.Code == c.Code {
+			return errors.New("code is already used by " + other.Name)
+		}
+		if other.Kind == c.Kind && CategoryKey(other.Name) == CategoryKey(c.Name) {
+			return errors.New("a category with this name already exists")
+		}
+	}
+
+	if c.ParentID == "" {
+		return nil
+	}
+	if c.ParentID == c.ID {
+		return errors.New("a category cannot be its own parent")
+	}
+	for _, p := range existing {
+		if p.ID != c.ParentID {
+			continue
+		}
+		if p.Kind != c.Kind {
+			return errors.New("parent category must have the same kind")
+		}
+		// One level of nesting keeps reports readable and rules out cycles.
+		if p.ParentID != "" {
+			return errors.New("parent category must be a top-level category")
+		}
+		for _, child := range existing {
+			if child.ParentID == c.ID && c.ID != "" {
+				return errors.New("a category with subcategories cannot have a parent")
+			}
+		}
+		return nil
+	}
+	return errors.New("parent category not found")
+}
+
+// ResolveCategory finds the category a transaction of the given type refers
+// to, by ID or else by name or code. Only active categories of the matching
+// kind are accepted.
+func ResolveCategory(categories []Category, id, name, kind string) (Category, error) {
+	var found *Category
+	for i := range categories {
+		c := &categories[i]
+		if id != "" {
+			if c.ID == id {
+				found = c
+				break
+			}
+			continue
+		}
+		if c.Kind != kind {
+			continue
+		}
+		if CategoryKey(c.Name) == CategoryKey(name) || strings.EqualFold(c.Code, strings.TrimSpace(name)) {
+			found = c
+			if c.Active {
+				break
+			}
+		}
+	}
+	if found == nil {
+		return Category{}, ErrCategoryNotFound
+	}
+	if !found.Active {
+		return Category{}, ErrCategoryInactive
+	}
+	if found.Kind != kind {
+		return Category{}, ErrCategoryKindInvalid
+	}
+	return *found, nil
+}
+
+// DefaultCategories is the starting set created at setup.
+func DefaultCategories() []Category {
+	defaults := []struct{ kind, name string }{
+		{"income", "Iuran Warga"},
+		{"income", "Donasi"},
+		{"income", "Pemasukan Lain-lain"},
+		{"expense", "Kebersihan"},
+		{"expense", "Keamanan"},
+		{"expense", "Listrik dan Air"},
+		{"expense", "Kegiatan Warga"},
+		{"expense", "Pengeluaran Lain-lain"},
+	}
+	categories := make([]Category, len(defaults))
+	for i, d := range defaults {
+		categories[i] = Category{Kind: d.kind, Code: CategoryCode(d.name), Name: d.name, Active: true}
+	}
+	return categories
+}
+
+type CategoryRequest struct {
+	Kind     string `json:"kind"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+	Active   *bool  `json:"active"`
+}
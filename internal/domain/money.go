@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount of rupiah stored in sen (1/100 rupiah), so sums and
+// comparisons are exact. In JSON it is a plain number of rupiah.
+type Money int64
+
+const senPerRupiah = 100
+
+var (
+	// ErrInvalidAmount is wrapped by every error from ParseMoney and
+	// Money.UnmarshalJSON.
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	errMoneyFormat    = ErrInvalidAmount
+	errMoneyPrecision = fmt.Errorf("%w: more than two decimal places", ErrInvalidAmount)
+	errMoneyRange     = fmt.Errorf("%w: out of range", ErrInvalidAmount)
+
+	// thousandsGrouped matches "1.250.000" style numbers without a decimal
+	// comma, where every dot is a thousands separator.
+	thousandsGrouped = regexp.MustCompile(`^\d{1,3}(\.\d{3})+$`)
+	plainDecimal     = regexp.MustCompile(`^\d+(\.\d+)?$`)
+)
+
+func Rupiah(r int64) Money {
+	return Money(r * senPerRupiah)
+}
+
+// ParseMoney reads an amount as people type it: "150000", "150000.50",
+// "Rp 1.250.000", "1.250.000,50" or "IDR 1,250,000.00".
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = strings.TrimSpace(s[1:])
+	}
+	upper := strings.ToUpper(s)
+	for _, prefix := range []string{"RP.", "RP", "IDR"} {
+		if strings.HasPrefix(upper, prefix) {
+			s = strings.TrimSpace(s[len(prefix):])
+			break
+		}
+	}
+	if strings.HasPrefix(s, "-") && !neg {
+		neg = true
+		s = strings.TrimSpace(s[1:])
+	}
+	s = strings.ReplaceAll(s, " ", "")
+	s = strings.TrimSuffix(s, ",-")
+
+	dots := strings.Count(s, ".")
+	commas := strings.Count(s, ",")
+	switch {
+	case dots > 0 && commas > 0:
+		// Whichever separator comes last is the decimal one.
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case commas > 1:
+		s = strings.ReplaceAll(s, ",", "")
+	case commas == 1:
+		s = strings.Replace(s, ",", ".", 1)
+	case dots > 1 || thousandsGrouped.MatchString(s):
+		s = strings.ReplaceAll(s, ".", "")
+	}
+
+	if !plainDecimal.MatchString(s) {
+		return 0, errMoneyFormat
+	}
+	m, err := parseDecimal(s, false)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+// parseDecimal converts a decimal literal, as validated by ParseMoney or the
+// JSON decoder, to sen. Literals finer than a sen are rejected unless round
+// is set.
+func parseDecimal(s string, round bool) (Money, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, errMoneyFormat
+	}
+	r.Mul(r, big.NewRat(senPerRupiah, 1))
+	if !r.IsInt() {
+		if !round {
+			return 0, errMoneyPrecision
+		}
+		// Half away from zero.
+		half := big.NewRat(1, 2)
+		if r.Sign() < 0 {
+			half.Neg(half)
+		}
+		r.Add(r, half)
+	}
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	if !n.IsInt64() {
+		return 0, errMoneyRange
+	}
+	return Money(n.Int64()), nil
+}
+
+// MoneyFromFloat converts a float64 amount of rupiah, as stored before
+// Money existed, rounding to the nearest sen.
+func MoneyFromFloat(f float64) (Money, error) {
+	return parseDecimal(strconv.FormatFloat(f, 'f', -1, 64), true)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON accepts a JSON number, parsed exactly, or a formatted string
+// such as "Rp 1.250.000".
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		v, err := ParseMoney(s)
+		if err != nil {
+			return fmt.Errorf("%w: %q", err, s)
+		}
+		*m = v
+		return nil
+	}
+	v, err := parseDecimal(string(data), false)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, data)
+	}
+	*m = v
+	return nil
+}
+
+// Decimal renders m as a plain decimal number of rupiah: "150000" or
+// "1250.50".
+func (m Money) Decimal() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/senPerRupiah, v%senPerRupiah
+	if frac == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, whole, frac)
+}
+
+// String formats m the Indonesian way: "Rp 1.250.000" or "Rp 1.250.000,50".
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	digits := strconv.FormatInt(v/senPerRupiah, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	if frac := v % senPerRupiah; frac != 0 {
+		fmt.Fprintf(&b, ",%02d", frac)
+	}
+	return sign + "Rp " + b.String()
+}
+
+// Float returns the amount in rupiah, for display and charts only.
+func (m Money) Float() float64 {
+	return float64(m) / senPerRupiah
+}
+
+// AQLValue makes queries compare amounts in rupiah, the unit the API uses.
+func (m Money) AQLValue() interface{} {
+	return m.Float()
+}
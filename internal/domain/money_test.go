@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr error
+	}{
+		{in: "150000", want: Rupiah(150000)},
+		{in: "150000.50", want: 15000050},
+		{in: "Rp 1.250.000", want: Rupiah(1250000)},
+		{in: "Rp. 1.250.000", want: Rupiah(1250000)},
+		{in: "rp1.250.000", want: Rupiah(1250000)},
+		{in: "1.250.000,50", want: 125000050},
+		{in: "IDR 1,250,000.00", want: Rupiah(1250000)},
+		{in: "1.500", want: Rupiah(1500)},
+		{in: "1,5", want: 150},
+		{in: "Rp 25.000,-", want: Rupiah(25000)},
+		{in: "-Rp 5.000", want: Rupiah(-5000)},
+		{in: "Rp -5.000", want: Rupiah(-5000)},
+		{in: "  75 000 ", want: Rupiah(75000)},
+		{in: "1.234,567", wantErr: errMoneyPrecision},
+		{in: "0,001", wantErr: errMoneyPrecision},
+		{in: "99999999999999999999", wantErr: errMoneyRange},
+		{in: "1.2.3,4,5", wantErr: ErrInvalidAmount},
+		{in: "Rp", wantErr: ErrInvalidAmount},
+		{in: "seratus", wantErr: ErrInvalidAmount},
+		{in: "", wantErr: ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseMoney(%q) = %d, %v; want %v", tt.in, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	tests := []struct {
+		m       Money
+		str     string
+		decimal string
+	}{
+		{m: 0, str: "Rp 0", decimal: "0"},
+		{m: Rupiah(500), str: "Rp 500", decimal: "500"},
+		{m: Rupiah(1250000), str: "Rp 1.250.000", decimal: "1250000"},
+		{m: 125000050, str: "Rp 1.250.000,50", decimal: "1250000.50"},
+		{m: 5, str: "Rp 0,05", decimal: "0.05"},
+		{m: Rupiah(-5000), str: "-Rp 5.000", decimal: "-5000"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.str {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.m, got, tt.str)
+		}
+		if got := tt.m.Decimal(); got != tt.decimal {
+			t.Errorf("Money(%d).Decimal() = %q, want %q", tt.m, got, tt.decimal)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `150000`, want: Rupiah(150000)},
+		{in: `1250.5`, want: 125050},
+		{in: `"Rp 1.000"`, want: Rupiah(1000)},
+		{in: `null`, want: 0},
+		{in: `1.001`, wantErr: true},
+		{in: `"banyak"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(tt.in), &m)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("Unmarshal(%s) = %d, %v; want an invalid amount", tt.in, m, err)
+			}
+			continue
+		}
+		if err != nil || m != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, %v; want %d", tt.in, m, err, tt.want)
+		}
+	}
+
+	out, err := json.Marshal(Money(125050))
+	if err != nil || string(out) != "1250.50" {
+		t.Errorf("Marshal = %s, %v; want 1250.50", out, err)
+	}
+}
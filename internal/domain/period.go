@@ -0,0 +1,75 @@
+package domain
+
+import "time"
+
+// ClosedPeriod is a month after tutup buku: its totals as read out to the
+// residents, and the lock on its transactions. A reopened period keeps its
+// record with ReopenedAt set; closing it again adds a new one.
+type ClosedPeriod struct {
+	ID string `json:"id"`
+	// Start is inclusive and End exclusive, both midnight in the timezone
+	// the period was closed in.
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Label            string    `json:"label"`
+	OpeningBalance   Money     `json:"opening_balance"`
+	TotalIncome      Money     `json:"total_income"`
+	TotalExpense     Money     `json:"total_expense"`
+	ClosingBalance   Money     `json:"closing_balance"`
+	TransactionCount int       `json:"transaction_count"`
+	Note             string    `json:"note,omitempty"`
+
+	ClosedAt         time.Time `json:"closed_at"`
+	ClosedBy         string    `json:"closed_by"`
+	ClosedByUsername string    `json:"closed_by_username,omitempty"`
+
+	ReopenedAt         *time.Time `json:"reopened_at,omitempty"`
+	ReopenedBy         string     `json:"reopened_by,omitempty"`
+	ReopenedByUsername string     `json:"reopened_by_username,omitempty"`
+	ReopenReason       string     `json:"reopen_reason,omitempty"`
+}
+
+// Locked reports whether the period still locks its transactions.
+func (p ClosedPeriod) Locked() bool {
+	return p.ReopenedAt == nil
+}
+
+func (p ClosedPeriod) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// LockedPeriod returns the locked period containing t, if any.
+func LockedPeriod(periods []ClosedPeriod, t time.Time) (ClosedPeriod, bool) {
+	for _, p := range periods {
+		if p.Locked() && p.Contains(t) {
+			return p, true
+		}
+	}
+	return ClosedPeriod{}, false
+}
+
+// NewClosedPeriod closes [start, end) with the totals in s, a summary of
+// that range.
+func NewClosedPeriod(start, end time.Time, label string, s Summary, a Actor, now time.Time) ClosedPeriod {
+	return ClosedPeriod{
+		Start:            start,
+		End:              end,
+		Label:            label,
+		OpeningBalance:   s.OpeningBalance,
+		TotalIncome:      s.TotalIncome,
+		TotalExpense:     s.TotalExpense,
+		ClosingBalance:   s.ClosingBalance,
+		TransactionCount: s.TransactionCount,
+		ClosedAt:         now,
+		ClosedBy:         a.UserID,
+		ClosedByUsername: a.Username,
+	}
+}
+
+// Reopen unlocks the period.
+func (p *ClosedPeriod) Reopen(reason string, a Actor, now time.Time) {
+	p.ReopenedAt = &now
+	p.ReopenedBy = a.UserID
+	p.ReopenedByUsername = a.Username
+	p.ReopenReason = reason
+}
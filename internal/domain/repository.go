@@ -0,0 +1,91 @@
+package domain
+
+// Repositories are the storage API the handlers depend on. Reads return
+// copies that are safe to keep and modify; every write is durable before the
+// in-memory state changes.
+
+type TransactionRepository interface {
+	Transactions() []Transaction
+	Transaction(id string) (Transaction, bool)
+	// QueryTransactions runs an AQL PANEN statement and returns the matching
+	// page along with the number of matches before BATAS/LEWATI.
+	QueryTransactions(query string) ([]Transaction, int, error)
+	InsertTransaction(tx Transaction) error
+	UpdateTransaction(tx Transaction) error
+}
+
+type UserRepository interface {
+	Users() []User
+	User(id string) (User, bool)
+	UserByUsername(username string) (User, bool)
+	UserCount() int
+	InsertUser(u User) error
+	UpdateUser(u User) error
+	DeleteUser(id string) error
+}
+
+type CategoryRepository interface {
+	Categories() []Category
+	Category(id string) (Category, bool)
+	InsertCategory(c Category) error
+	UpdateCategory(c Category) error
+	DeleteCategory(id string) error
+}
+
+type AuditLogRepository interface {
+	AuditLogs() []AuditLog
+	AuditLog(id string) (AuditLog, bool)
+	InsertAuditLog(log AuditLog) error
+}
+
+type SettingsRepository interface {
+	Settings() AppSettings
+	SaveSettings(s AppSettings) error
+}
+
+type PeriodRepository interface {
+	ClosedPeriods() []ClosedPeriod
+	InsertClosedPeriod(p ClosedPeriod) error
+	UpdateClosedPeriod(p ClosedPeriod) error
+}
+
+// Repositories groups every repository over one consistent state.
+type Repositories interface {
+	TransactionRepository
+	UserRepository
+	CategoryRepository
+	AuditLogRepository
+	SettingsRepository
+	PeriodRepository
+}
+
+type UnitOfWork interface {
+	// Update runs fn with exclusive access to the store. Writes made through
+	// tx are staged and committed together once fn returns nil, or discarded
+	// if it returns an error. Reads through tx do not see staged writes.
+	Update(fn func(tx Repositories) error) error
+	// View runs fn with a read-only snapshot of the committed state. Writers
+	// wait until fn returns, so every read through s sees the same state.
+	View(fn func(s Snapshot) error) error
+}
+
+// Snapshot is one committed state of the store.
+type Snapshot interface {
+	Settings() AppSettings
+	Summary(q SummaryQuery) Summary
+	Transactions() []Transaction
+	Categories() []Category
+	AuditLogs() []AuditLog
+	ClosedPeriods() []ClosedPeriod
+}
+
+// SummaryReader answers aggregate queries over the committed state.
+type SummaryReader interface {
+	Summary(q SummaryQuery) Summary
+}
+
+type Store interface {
+	Repositories
+	UnitOfWork
+	SummaryReader
+}
@@ -1,9 +1,51 @@
 package domain
 
+import (
+	"fmt"
+	"time"
+)
+
 type AppSettings struct {
-	RTName       string `json:"rt_name"`
-	RWName       string `json:"rw_name"`
-	Kelurahan    string `json:"kelurahan"`
-	Kecamatan    string `json:"kecamatan"`
-	Address      string `json:"address"`
+	RTName    string `json:"rt_name"`
+	RWName    string `json:"rw_name"`
+	Kelurahan string `json:"kelurahan"`
+	Kecamatan string `json:"kecamatan"`
+	Address   string `json:"address"`
+	// Signatories printed on reports.
+	KetuaRTName   string `json:"ketua_rt_name,omitempty"`
+	BendaharaName string `json:"bendahara_name,omitempty"`
+	// MaxBackdateDays limits how many days before today a transaction may
+	// be dated. Zero means no limit. Imports are not limited.
+	MaxBackdateDays int `json:"max_backdate_days,omitempty"`
+	// ImmutableLedger turns edits of posted transactions into a reversing
+	// entry plus a corrected entry, and deletes into a reversing entry.
+	ImmutableLedger bool `json:"immutable_ledger,omitempty"`
+}
+
+// Validate checks the settings an admin submits.
+func (s AppSettings) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if s.MaxBackdateDays < 0 {
+		errs.Add("max_backdate_days", "invalid", "max_backdate_days must not be negative")
+	}
+	return errs
+}
+
+// CheckTransactionDate rejects transaction dates after today and, with
+// MaxBackdateDays set, more than that many days before today. Days are
+// calendar days in loc.
+func (s AppSettings) CheckTransactionDate(date, now time.Time, loc *time.Location) ValidationErrors {
+	var errs ValidationErrors
+	day := func(t time.Time) time.Time {
+		y, m, d := t.In(loc).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	today := day(now)
+	switch {
+	case day(date).After(today):
+		errs.Add("transaction_date", "future", "transaction_date cannot be in the future")
+	case s.MaxBackdateDays > 0 && day(date).Before(today.AddDate(0, 0, -s.MaxBackdateDays)):
+		errs.Add("transaction_date", "too_old", fmt.Sprintf("transaction_date cannot be more than %d days ago", s.MaxBackdateDays))
+	}
+	return errs
 }
@@ -0,0 +1,194 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Summary intervals for SummaryQuery.Interval.
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// SummaryQuery selects the transactions a Summary covers. From is inclusive
+// and To exclusive; either may be nil for an open range. Buckets start at
+// midnight in Location, weeks on Monday.
+type SummaryQuery struct {
+	From     *time.Time
+	To       *time.Time
+	Location *time.Location
+	Interval string
+}
+
+// Key identifies the query for caching.
+func (q SummaryQuery) Key() string {
+	key := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", key(q.From), key(q.To), q.location(), q.Interval)
+}
+
+func (q SummaryQuery) location() *time.Location {
+	if q.Location == nil {
+		return time.UTC
+	}
+	return q.Location
+}
+
+type CategorySummary struct {
+	CategoryID string `json:"category_id,omitempty"`
+	Category   string `json:"category"`
+	Type       string `json:"type"`
+	Total      Money  `json:"total"`
+	Count      int    `json:"count"`
+}
+
+// SummaryBucket is one interval of the time series. Balance is the running
+// balance at the end of the bucket, including the opening balance.
+type SummaryBucket struct {
+	Start   time.Time `json:"start"`
+	Income  Money     `json:"income"`
+	Expense Money     `json:"expense"`
+	Net     Money     `json:"net"`
+	Balance Money     `json:"balance"`
+	Count   int       `json:"count"`
+}
+
+type Summary struct {
+	From             *time.Time        `json:"from,omitempty"`
+	To               *time.Time        `json:"to,omitempty"`
+	Timezone         string            `json:"timezone"`
+	Interval         string            `json:"interval"`
+	OpeningBalance   Money             `json:"opening_balance"`
+	TotalIncome      Money             `json:"total_income"`
+	TotalExpense     Money             `json:"total_expense"`
+	Balance          Money             `json:"balance"`
+	ClosingBalance   Money             `json:"closing_balance"`
+	TransactionCount int               `json:"transaction_count"`
+	Categories       []CategorySummary `json:"categories"`
+	// Series only has buckets that contain transactions.
+	Series []SummaryBucket `json:"series"`
+}
+
+// Summarize aggregates the live transactions in txs. Category names are
+// taken from categories when the transaction is linked to one, so renamed
+// categories are reported under their current name.
+func Summarize(txs []Transaction, categories []Category, q SummaryQuery) Summary {
+	loc := q.location()
+	s := Summary{
+		From:       q.From,
+		To:         q.To,
+		Timezone:   loc.String(),
+		Interval:   q.Interval,
+		Categories: []CategorySummary{},
+		Series:     []SummaryBucket{},
+	}
+
+	names := make(map[string]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	type categoryKey struct{ id, name, kind string }
+	byCategory := map[categoryKey]*CategorySummary{}
+	byBucket := map[time.Time]*SummaryBucket{}
+
+	for _, tx := range txs {
+		if tx.DeletedAt != nil {
+			continue
+		}
+		at := tx.Date()
+		if q.To != nil && !at.Before(*q.To) {
+			continue
+		}
+		signed := tx.Amount
+		if tx.Type != "income" {
+			signed = -signed
+		}
+		if q.From != nil && at.Before(*q.From) {
+			s.OpeningBalance += signed
+			continue
+		}
+
+		s.TransactionCount++
+		if tx.Type == "income" {
+			s.TotalIncome += tx.Amount
+		} else {
+			s.TotalExpense += tx.Amount
+		}
+
+		name := tx.Category
+		if n, ok := names[tx.CategoryID]; ok {
+			name = n
+		}
+		ck := categoryKey{tx.CategoryID, name, tx.Type}
+		cs := byCategory[ck]
+		if cs == nil {
+			cs = &CategorySummary{CategoryID: tx.CategoryID, Category: name, Type: tx.Type}
+			byCategory[ck] = cs
+		}
+		cs.Total += tx.Amount
+		cs.Count++
+
+		start := bucketStart(at.In(loc), q.Interval)
+		b := byBucket[start]
+		if b == nil {
+			b = &SummaryBucket{Start: start}
+			byBucket[start] = b
+		}
+		if tx.Type == "income" {
+			b.Income += tx.Amount
+		} else {
+			b.Expense += tx.Amount
+		}
+		b.Net += signed
+		b.Count++
+	}
+
+	s.Balance = s.TotalIncome - s.TotalExpense
+	s.ClosingBalance = s.OpeningBalance + s.Balance
+
+	for _, cs := range byCategory {
+		s.Categories = append(s.Categories, *cs)
+	}
+	sort.Slice(s.Categories, func(i, j int) bool {
+		a, b := s.Categories[i], s.Categories[j]
+		if a.Type != b.Type {
+			return a.Type == "income"
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Category < b.Category
+	})
+
+	for _, b := range byBucket {
+		s.Series = append(s.Series, *b)
+	}
+	sort.Slice(s.Series, func(i, j int) bool { return s.Series[i].Start.Before(s.Series[j].Start) })
+	balance := s.OpeningBalance
+	for i := range s.Series {
+		balance += s.Series[i].Net
+		s.Series[i].Balance = balance
+	}
+	return s
+}
+
+func bucketStart(t time.Time, interval string) time.Time {
+	y, m, d := t.Date()
+	switch interval {
+	case IntervalDay:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	case IntervalWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	}
+}
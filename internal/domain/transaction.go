@@ -1,14 +1,157 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 type Transaction struct {
-	ID          string    `json:"id"`
-	Type        string    `json:"type"` // income | expense
-	Amount      float64   `json:"amount"`
-	Category    string    `json:"category"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	CreatedBy   string    `json:"created_by"`
+	ID          string     `json:"id"`
+	Type        string     `json:"type"` // income | expense
+	Amount      Money      `json:"amount"`
+	Category    string     `json:"category"`
+	CategoryID  string     `json:"category_id,omitempty"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   string     `json:"created_by"`
 	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+
+	CreatedByUsername string     `json:"created_by_username,omitempty"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy         string     `json:"updated_by,omitempty"`
+	UpdatedByUsername string     `json:"updated_by_username,omitempty"`
+
+	// ImportJob is the import that created the transaction, if any.
+	ImportJob string `json:"import_job,omitempty"`
+
+	// TransactionDate is when the money changed hands, which may be before
+	// the transaction was entered. Records written before it existed have
+	// none; see Date.
+	TransactionDate *time.Time `json:"transaction_date,omitempty"`
+
+	// AdjustmentOf is the transaction, usually in a closed period, that
+	// this adjusting entry corrects.
+	AdjustmentOf string `json:"adjustment_of,omitempty"`
+
+	// In immutable-ledger mode a posted transaction is never changed.
+	// ReversalOf marks the entry that cancels it and CorrectionOf the entry
+	// that replaces it.
+	ReversalOf   string `json:"reversal_of,omitempty"`
+	CorrectionOf string `json:"correction_of,omitempty"`
+}
+
+// Date is the transaction date, falling back to CreatedAt for legacy
+// records. Listings, summaries and reports are all by this date.
+func (t Transaction) Date() time.Time {
+	if t.TransactionDate != nil {
+		return *t.TransactionDate
+	}
+	return t.CreatedAt
+}
+
+var ErrInvalidDate = errors.New("transaction_date must be a date (YYYY-MM-DD) or RFC 3339 time")
+
+// ParseTransactionDate accepts YYYY-MM-DD, meaning midnight in loc, or an
+// RFC 3339 timestamp.
+func ParseTransactionDate(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+// Touch records a as the last user to change the transaction.
+func (t *Transaction) Touch(a Actor, now time.Time) {
+	t.UpdatedAt = &now
+	t.UpdatedBy = a.UserID
+	t.UpdatedByUsername = a.Username
+}
+
+// Reverse returns the entry that cancels t: the same type and category
+// with the amount negated, so totals per category net to zero.
+func (t Transaction) Reverse(id string, a Actor, now time.Time) Transaction {
+	return Transaction{
+		ID:                id,
+		Type:              t.Type,
+		Amount:            -t.Amount,
+		Category:          t.Category,
+		CategoryID:        t.CategoryID,
+		Description:       t.Description,
+		TransactionDate:   &now,
+		CreatedAt:         now,
+		CreatedBy:         a.UserID,
+		CreatedByUsername: a.Username,
+		ReversalOf:        t.ID,
+	}
+}
+
+// Correct returns corrected, the edited copy of t, as a new entry replacing
+// t. It keeps corrected's date and takes the rest of its history afresh.
+func (t Transaction) Correct(corrected Transaction, id string, a Actor, now time.Time) Transaction {
+	return Transaction{
+		ID:                id,
+		Type:              corrected.Type,
+		Amount:            corrected.Amount,
+		Category:          corrected.Category,
+		CategoryID:        corrected.CategoryID,
+		Description:       corrected.Description,
+		TransactionDate:   corrected.TransactionDate,
+		CreatedAt:         now,
+		CreatedBy:         a.UserID,
+		CreatedByUsername: a.Username,
+		CorrectionOf:      t.ID,
+	}
+}
+
+// Reversed reports whether txs hold a reversing entry for the transaction
+// id.
+func Reversed(txs []Transaction, id string) bool {
+	for _, tx := range txs {
+		if tx.ReversalOf == id && tx.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// restorableFields are the fields an audit restore may put back.
+var restorableFields = []string{"type", "amount", "category", "category_id", "description", "transaction_date"}
+
+// Revert undoes the restorable fields in changes, setting each back to its
+// Before value. When only the category name is known, as in legacy entries,
+// CategoryID is cleared so the name is resolved again.
+func (t Transaction) Revert(changes map[string]FieldChange) (Transaction, error) {
+	current, err := json.Marshal(t)
+	if err != nil {
+		return t, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(current, &fields); err != nil {
+		return t, err
+	}
+
+	_, nameChanged := changes["category"]
+	_, idChanged := changes["category_id"]
+	for _, f := range restorableFields {
+		if c, ok := changes[f]; ok && c.Before != nil {
+			fields[f] = c.Before
+		}
+	}
+	if nameChanged && !idChanged {
+		delete(fields, "category_id")
+	}
+
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return t, err
+	}
+	var reverted Transaction
+	if err := json.Unmarshal(merged, &reverted); err != nil {
+		return t, err
+	}
+	return reverted, nil
 }
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const MaxDescriptionLength = 500
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Field + ": " + e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (v *ValidationErrors) Add(field, code, message string) {
+	*v = append(*v, FieldError{Field: field, Code: code, Message: message})
+}
+
+// TransactionInput is the client-writable part of a transaction. ID,
+// timestamps, author and deletion state are owned by the server and cannot
+// be set through it.
+type TransactionInput struct {
+	Type        string `json:"type"`
+	Amount      *Money `json:"amount"`
+	Category    string `json:"category"`
+	CategoryID  string `json:"category_id"`
+	Description string `json:"description"`
+	// TransactionDate is YYYY-MM-DD or an RFC 3339 time; it defaults to
+	// the time of entry.
+	TransactionDate string `json:"transaction_date"`
+	// AdjustmentOf marks a new transaction as an adjusting entry for an
+	// existing one. It is ignored on update.
+	AdjustmentOf string `json:"adjustment_of"`
+}
+
+// Normalize trims and canonicalizes the text fields.
+func (in *TransactionInput) Normalize() {
+	in.Type = strings.ToLower(strings.TrimSpace(in.Type))
+	in.Category = NormalizeCategoryName(in.Category)
+	in.CategoryID = strings.TrimSpace(in.CategoryID)
+	in.Description = NormalizeText(in.Description)
+	in.TransactionDate = strings.TrimSpace(in.TransactionDate)
+	in.AdjustmentOf = strings.TrimSpace(in.AdjustmentOf)
+}
+
+// Validate checks a normalized input. For partial updates, fields left
+// empty keep their current value and are not required.
+func (in TransactionInput) Validate(partial bool) ValidationErrors {
+	var errs ValidationErrors
+
+	switch {
+	case in.Type == "" && partial:
+	case in.Type == "":
+		errs.Add("type", "required", "type is required")
+	case in.Type != "income" && in.Type != "expense":
+		errs.Add("type", "invalid", "type must be income or expense")
+	}
+
+	switch {
+	case in.Amount == nil && partial:
+	case in.Amount == nil:
+		errs.Add("amount", "required", "amount is required")
+	case *in.Amount <= 0:
+		errs.Add("amount", "not_positive", "amount must be greater than zero")
+	}
+
+	if !partial && in.Category == "" && in.CategoryID == "" {
+		errs.Add("category", "required", "category is required")
+	}
+
+	if n := utf8.RuneCountInString(in.Description); n > MaxDescriptionLength {
+		errs.Add("description", "too_long", fmt.Sprintf("description must be at most %d characters", MaxDescriptionLength))
+	}
+
+	return errs
+}
+
+// NormalizeText collapses runs of whitespace into single spaces and drops
+// control characters.
+func NormalizeText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,218 @@
+// Package export writes transactions and audit log entries as CSV for
+// spreadsheets or as NDJSON for scripts, one record at a time.
+package export
+
+import (
+	"audit-sendiri/internal/domain"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats accepted by Options.Format. Beancount and ledger-cli journals
+// are only available for transactions.
+const (
+	FormatCSV       = "csv"
+	FormatNDJSON    = "ndjson"
+	FormatBeancount = "beancount"
+	FormatLedger    = "ledger"
+)
+
+var ErrUnsupportedFormat = errors.New("format must be csv, ndjson, beancount or ledger")
+
+type Options struct {
+	Format string
+	// BOM starts CSV output with a UTF-8 byte order mark, which Excel needs
+	// to read non-ASCII text correctly.
+	BOM bool
+	// DecimalComma writes amounts as 1250000,50 and separates fields with
+	// semicolons, as Excel expects under an Indonesian locale.
+	DecimalComma bool
+	// Location is the timezone of the date column. Timestamps are always
+	// RFC 3339.
+	Location *time.Location
+}
+
+// Validate checks the format.
+func (o Options) Validate() error {
+	switch o.Format {
+	case FormatCSV, FormatNDJSON, FormatBeancount, FormatLedger:
+		return nil
+	}
+	return ErrUnsupportedFormat
+}
+
+// Journal reports whether the format is a plain-text accounting journal.
+func (o Options) Journal() bool {
+	return o.Format == FormatBeancount || o.Format == FormatLedger
+}
+
+// ContentType is the MIME type of the output.
+func (o Options) ContentType() string {
+	switch o.Format {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func (o Options) location() *time.Location {
+	if o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
+
+func (o Options) amount(m domain.Money) string {
+	if o.DecimalComma {
+		return strings.Replace(m.Decimal(), ".", ",", 1)
+	}
+	return m.Decimal()
+}
+
+// transactionHeader uses the titles the importer recognizes, so an export
+// can be imported into a fresh instance.
+var transactionHeader = []string{
+	"id", "tanggal", "jenis", "kategori", "category_id", "keterangan", "jumlah",
+	"created_at", "created_by", "updated_at", "updated_by", "import_job",
+}
+
+// Transactions writes txs in the order given.
+func Transactions(w io.Writer, txs []domain.Transaction, opts Options) error {
+	if opts.Format == FormatNDJSON {
+		return writeNDJSON(w, len(txs), func(i int) interface{} { return txs[i] })
+	}
+	return writeCSV(w, opts, transactionHeader, len(txs), func(i int) []string {
+		tx := txs[i]
+		return []string{
+			tx.ID,
+			tx.Date().In(opts.location()).Format("2006-01-02"),
+			tx.Type,
+			text(tx.Category),
+			tx.CategoryID,
+			text(tx.Description),
+			opts.amount(tx.Amount),
+			timestamp(&tx.CreatedAt),
+			text(tx.CreatedByUsername),
+			timestamp(tx.UpdatedAt),
+			text(tx.UpdatedByUsername),
+			tx.ImportJob,
+		}
+	})
+}
+
+var auditLogHeader = []string{
+	"id", "created_at", "entity_type", "entity_id", "action", "note",
+	"created_by", "created_by_username", "ip_address", "import_job",
+	"before", "after", "details", "prev_hash", "hash",
+}
+
+// AuditLogs writes logs in the order given. Before and After are written as
+// JSON text in CSV.
+func AuditLogs(w io.Writer, logs []domain.AuditLog, opts Options) error {
+	if opts.Format == FormatNDJSON {
+		return writeNDJSON(w, len(logs), func(i int) interface{} { return logs[i] })
+	}
+	return writeCSV(w, opts, auditLogHeader, len(logs), func(i int) []string {
+		l := logs[i]
+		return []string{
+			l.ID,
+			timestamp(&l.CreatedAt),
+			l.EntityType,
+			l.EntityID,
+			l.Action,
+			text(l.Note),
+			l.CreatedBy,
+			text(l.CreatedByUsername),
+			l.IPAddress,
+			l.ImportJob,
+			string(l.Before),
+			string(l.After),
+			text(l.Details),
+			l.PrevHash,
+			l.Hash,
+		}
+	})
+}
+
+func writeCSV(w io.Writer, opts Options, header []string, n int, row func(i int) []string) error {
+	bw := bufio.NewWriter(w)
+	if opts.BOM {
+		if _, err := bw.WriteString("\xef\xbb\xbf"); err != nil {
+			return err
+		}
+	}
+	cw := csv.NewWriter(bw)
+	if opts.DecimalComma {
+		cw.Comma = ';'
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := cw.Write(row(i)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// text guards a free-text CSV cell against formula injection. Spreadsheets
+// evaluate a cell starting with =, +, - or @, and some skip a leading tab or
+// carriage return first, so cells starting with any of these are prefixed
+// with an apostrophe.
+func text(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func writeNDJSON(w io.Writer, n int, record func(i int) interface{}) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(record(i)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func timestamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// AuditEntry records that count records of dataset ("transactions" or
+// "audit_log") were exported with the given filter, so there is a trail of
+// who took resident financial data out of the system.
+func AuditEntry(dataset string, opts Options, filter string, count int, now time.Time) domain.AuditLog {
+	return domain.AuditLog{
+		EntityType: "export",
+		EntityID:   dataset,
+		Action:     "export",
+		Note:       fmt.Sprintf("Exported %d records from %s as %s", count, dataset, opts.Format),
+		After: domain.AuditState(map[string]interface{}{
+			"format":        opts.Format,
+			"filter":        filter,
+			"count":         count,
+			"decimal_comma": opts.DecimalComma,
+		}),
+		CreatedAt: now,
+	}
+}
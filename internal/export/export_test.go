@@ -0,0 +1,107 @@
+package export
+
+import (
+	"audit-sendiri/internal/domain"
+	"audit-sendiri/internal/importer"
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"Iuran Januari", "Iuran Januari"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+62 812", "'+62 812"},
+		{"-5000", "'-5000"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"\r=1+1", "'\r=1+1"},
+		{"a=b", "a=b"},
+		{"'quoted", "'quoted"},
+	}
+	for _, tt := range tests {
+		if got := text(tt.in); got != tt.want {
+			t.Errorf("text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTransactionsCSVGuardsFormulas(t *testing.T) {
+	date := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	txs := []domain.Transaction{{
+		ID:                "t1",
+		Type:              "expense",
+		Amount:            domain.Rupiah(-5000),
+		Category:          "@Kebersihan",
+		Description:       "=cmd|' /C calc'!A0",
+		CreatedAt:         date,
+		CreatedByUsername: "+admin",
+	}}
+	var buf bytes.Buffer
+	if err := Transactions(&buf, txs, Options{Format: FormatCSV}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := rows[1]
+	if row[3] != "'@Kebersihan" || row[5] != "'=cmd|' /C calc'!A0" || row[8] != "'+admin" {
+		t.Errorf("text cells = %q, %q, %q; want them prefixed", row[3], row[5], row[8])
+	}
+	// Amounts are numbers, not text, and keep their sign.
+	if row[6] != "-5000" {
+		t.Errorf("amount = %q, want -5000", row[6])
+	}
+}
+
+func TestAuditLogsCSVGuardsFormulas(t *testing.T) {
+	logs := []domain.AuditLog{{
+		ID:                "a1",
+		Action:            "login_failed",
+		Note:              "-1+1",
+		CreatedByUsername: "=x",
+		Details:           `{"a":"b"}`,
+	}}
+	var buf bytes.Buffer
+	if err := AuditLogs(&buf, logs, Options{Format: FormatCSV, DecimalComma: true}); err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(&buf)
+	r.Comma = ';'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := rows[1]
+	if row[5] != "'-1+1" || row[7] != "'=x" || row[12] != `{"a":"b"}` {
+		t.Errorf("text cells = %q, %q, %q", row[5], row[7], row[12])
+	}
+}
+
+func TestGuardedCSVImportsBack(t *testing.T) {
+	date := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	txs := []domain.Transaction{{
+		ID: "t1", Type: "expense", Amount: domain.Rupiah(5000), Category: "Kebersihan",
+		Description: "=1+1 sapu", CreatedAt: date, TransactionDate: &date,
+	}}
+	var buf bytes.Buffer
+	if err := Transactions(&buf, txs, Options{Format: FormatCSV}); err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := importer.ReadRows(buf.Bytes(), importer.FormatCSV, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categories := []domain.Category{{ID: "c1", Kind: "expense", Code: "KEBERSIHAN", Name: "Kebersihan", Active: true}}
+	job, err := importer.Parse(sheet, "export.csv", importer.FormatCSV, categories, importer.Options{Location: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ValidRows != 1 || job.Sample[0].Description != "=1+1 sapu" {
+		t.Errorf("imported %d rows, sample %+v; want the original description", job.ValidRows, job.Sample)
+	}
+}
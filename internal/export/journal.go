@@ -0,0 +1,341 @@
+package export
+
+import (
+	"audit-sendiri/internal/domain"
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Plain-text accounting accounts. Categories become Income:<Name> and
+// Expenses:<Name>.
+const (
+	AccountCash    = "Assets:Kas"
+	AccountOpening = "Equity:Saldo-Awal"
+	currency       = "IDR"
+)
+
+// Journal is the cash book of a period for plain-text accounting tools
+// (Beancount and ledger-cli). It always holds every live transaction of the
+// period, so the balance assertions at each month end can be checked
+// independently.
+type Journal struct {
+	Title    string
+	From     *time.Time
+	To       *time.Time
+	Location *time.Location
+	// Opening is the cash balance before From.
+	Opening domain.Money
+	Entries []JournalEntry
+}
+
+// JournalEntry is one transaction with its accounts resolved.
+type JournalEntry struct {
+	domain.Transaction
+	Date    time.Time
+	Account string
+	// AuditHash is the hash of the latest audit log entry for the
+	// transaction, linking the journal to the audit chain.
+	AuditHash string
+}
+
+// NewJournal selects the live transactions in [from, to) and orders them by
+// date. Category names are taken from categories when the transaction is
+// linked to one, as in summaries.
+func NewJournal(title string, txs []domain.Transaction, categories []domain.Category, logs []domain.AuditLog, from, to *time.Time, loc *time.Location) *Journal {
+	if loc == nil {
+		loc = time.UTC
+	}
+	j := &Journal{Title: title, From: from, To: to, Location: loc}
+
+	names := make(map[string]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+	hashes := map[string]string{}
+	for _, l := range logs {
+		if l.EntityType == "transaction" {
+			hashes[l.EntityID] = l.Hash
+		}
+	}
+
+	for _, tx := range txs {
+		if tx.DeletedAt != nil {
+			continue
+		}
+		if to != nil && !tx.Date().Before(*to) {
+			continue
+		}
+		if from != nil && tx.Date().Before(*from) {
+			if tx.Type == "income" {
+				j.Opening += tx.Amount
+			} else {
+				j.Opening -= tx.Amount
+			}
+			continue
+		}
+		name := tx.Category
+		if n, ok := names[tx.CategoryID]; ok {
+			name = n
+		}
+		prefix := "Expenses"
+		if tx.Type == "income" {
+			prefix = "Income"
+		}
+		j.Entries = append(j.Entries, JournalEntry{
+			Transaction: tx,
+			Date:        tx.Date().In(loc),
+			Account:     prefix + ":" + accountName(name),
+			AuditHash:   hashes[tx.ID],
+		})
+	}
+	sort.SliceStable(j.Entries, func(a, b int) bool { return j.Entries[a].Date.Before(j.Entries[b].Date) })
+	return j
+}
+
+// Write renders the journal as FormatBeancount or FormatLedger.
+func (j *Journal) Write(w io.Writer, format string) error {
+	bw := bufio.NewWriter(w)
+	jw := journalWriter{w: bw, beancount: format == FormatBeancount}
+	j.write(&jw)
+	if jw.err != nil {
+		return jw.err
+	}
+	return bw.Flush()
+}
+
+func (j *Journal) write(jw *journalWriter) {
+	jw.comment(j.Title)
+	if j.From != nil || j.To != nil {
+		jw.comment("Periode: " + j.periodLabel())
+	}
+	jw.comment("Setiap transaksi memuat id dan audit_hash (hash entri audit log terakhir untuk transaksi tersebut).")
+	if jw.beancount {
+		jw.printf("\noption \"title\" %s\n", beancountString(j.Title))
+		jw.printf("option \"operating_currency\" \"%s\"\n", currency)
+	}
+
+	start, ok := j.start()
+	if !ok {
+		return
+	}
+	if jw.beancount {
+		jw.printf("\n")
+		for _, account := range j.accounts() {
+			jw.printf("%s open %s %s\n", start.Format("2006-01-02"), account, currency)
+		}
+	}
+
+	balance := j.Opening
+	if j.Opening != 0 {
+		jw.printf("\n")
+		jw.transaction(start, "Saldo awal", nil, []posting{
+			{AccountCash, j.Opening},
+			{AccountOpening, -j.Opening},
+		})
+	}
+
+	// Balances are asserted at the end of every month and of the period.
+	boundary := nextMonth(start)
+	end := j.end()
+	assert := func(at time.Time) {
+		jw.printf("\n")
+		jw.assertion(at, balance)
+	}
+	for _, e := range j.Entries {
+		for !e.Date.Before(boundary) {
+			assert(boundary)
+			boundary = nextMonth(boundary)
+		}
+		signed := e.Amount
+		if e.Type != "income" {
+			signed = -signed
+		}
+		balance += signed
+
+		narration := e.Description
+		if narration == "" {
+			narration = e.Category
+		}
+		meta := [][2]string{{"id", e.ID}}
+		if e.AuditHash != "" {
+			meta = append(meta, [2]string{"audit_hash", e.AuditHash})
+		}
+		for _, link := range [][2]string{
+			{"import_job", e.ImportJob},
+			{"adjustment_of", e.AdjustmentOf},
+			{"reversal_of", e.ReversalOf},
+			{"correction_of", e.CorrectionOf},
+		} {
+			if link[1] != "" {
+				meta = append(meta, link)
+			}
+		}
+		jw.printf("\n")
+		jw.transaction(e.Date, narration, meta, []posting{
+			{e.Account, -signed},
+			{AccountCash, signed},
+		})
+	}
+	for boundary.Before(end) {
+		assert(boundary)
+		boundary = nextMonth(boundary)
+	}
+	assert(end)
+}
+
+// start is the first day of the journal: From, or the day of the first
+// transaction when the period is open.
+func (j *Journal) start() (time.Time, bool) {
+	switch {
+	case j.From != nil:
+		return startOfDay(j.From.In(j.Location)), true
+	case len(j.Entries) > 0:
+		return startOfDay(j.Entries[0].Date), true
+	}
+	return time.Time{}, false
+}
+
+// end is the day after the journal: To, or the first of the month after the
+// last transaction when the period is open.
+func (j *Journal) end() time.Time {
+	if j.To != nil {
+		return startOfDay(j.To.In(j.Location).Add(-time.Nanosecond)).AddDate(0, 0, 1)
+	}
+	if len(j.Entries) > 0 {
+		return nextMonth(j.Entries[len(j.Entries)-1].Date)
+	}
+	start, _ := j.start()
+	return nextMonth(start)
+}
+
+func (j *Journal) periodLabel() string {
+	from, to := "awal", "sekarang"
+	if j.From != nil {
+		from = j.From.In(j.Location).Format("2006-01-02")
+	}
+	if j.To != nil {
+		to = j.end().AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	return from + " s.d. " + to
+}
+
+func (j *Journal) accounts() []string {
+	seen := map[string]bool{AccountCash: true}
+	accounts := []string{AccountCash}
+	if j.From != nil {
+		seen[AccountOpening] = true
+		accounts = append(accounts, AccountOpening)
+	}
+	var rest []string
+	for _, e := range j.Entries {
+		if !seen[e.Account] {
+			seen[e.Account] = true
+			rest = append(rest, e.Account)
+		}
+	}
+	sort.Strings(rest)
+	return append(accounts, rest...)
+}
+
+type posting struct {
+	account string
+	amount  domain.Money
+}
+
+// journalWriter writes Beancount or ledger-cli syntax, keeping the first
+// write error.
+type journalWriter struct {
+	w         *bufio.Writer
+	beancount bool
+	err       error
+}
+
+func (jw *journalWriter) printf(format string, args ...interface{}) {
+	if jw.err == nil {
+		_, jw.err = fmt.Fprintf(jw.w, format, args...)
+	}
+}
+
+func (jw *journalWriter) comment(text string) {
+	prefix := ";"
+	if !jw.beancount {
+		prefix = ";;"
+	}
+	jw.printf("%s %s\n", prefix, text)
+}
+
+func (jw *journalWriter) transaction(date time.Time, narration string, meta [][2]string, postings []posting) {
+	if jw.beancount {
+		jw.printf("%s * %s\n", date.Format("2006-01-02"), beancountString(narration))
+		for _, m := range meta {
+			jw.printf("  %s: %s\n", m[0], beancountString(m[1]))
+		}
+		for _, p := range postings {
+			jw.printf("  %-36s %14s %s\n", p.account, p.amount.Decimal(), currency)
+		}
+		return
+	}
+	jw.printf("%s * %s\n", date.Format("2006/01/02"), ledgerText(narration))
+	for _, m := range meta {
+		jw.printf("    ; %s: %s\n", m[0], m[1])
+	}
+	for _, p := range postings {
+		jw.printf("    %-34s %14s %s\n", p.account, p.amount.Decimal(), currency)
+	}
+}
+
+// assertion checks the cash balance at the start of day at, i.e. after
+// every transaction before it. Beancount asserts at the start of the given
+// day; ledger-cli asserts after the postings of the day before.
+func (jw *journalWriter) assertion(at time.Time, balance domain.Money) {
+	if jw.beancount {
+		jw.printf("%s balance %s %14s %s\n", at.Format("2006-01-02"), AccountCash, balance.Decimal(), currency)
+		return
+	}
+	day := at.AddDate(0, 0, -1)
+	jw.printf("%s * Saldo kas per %s\n", day.Format("2006/01/02"), day.Format("2006-01-02"))
+	jw.printf("    %-34s %14s %s = %s %s\n", AccountCash, "0", currency, balance.Decimal(), currency)
+}
+
+// accountName turns a category name into an account component: words are
+// capitalized and joined with hyphens, so "iuran warga" becomes
+// "Iuran-Warga".
+func accountName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	if len(words) == 0 {
+		return "Lainnya"
+	}
+	return strings.Join(words, "-")
+}
+
+func beancountString(s string) string {
+	return strconv.Quote(strings.ReplaceAll(s, "\n", " "))
+}
+
+// ledgerText keeps a payee on one line; a semicolon would start a comment.
+func ledgerText(s string) string {
+	return strings.NewReplacer("\n", " ", ";", ",").Replace(s)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func nextMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,510 @@
+// Package importer turns CSV and XLSX exports of an existing cash book into
+// transactions: rows are mapped, validated and previewed first, then
+// committed in a single batch.
+package importer
+
+import (
+	"audit-sendiri/internal/domain"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSample is the number of parsed transactions a preview shows.
+const MaxSample = 50
+
+var ErrInvalidRows = errors.New("import has invalid rows; fix them and run the preview again")
+
+// Mapping names the column holding each field, by header text
+// (case-insensitive) or 1-based column number. Amounts come either from
+// Amount, signed or with a Type column, or from separate Income and Expense
+// columns as in a paper cash book.
+type Mapping struct {
+	Date        string `json:"date"`
+	Type        string `json:"type,omitempty"`
+	Amount      string `json:"amount,omitempty"`
+	Income      string `json:"income,omitempty"`
+	Expense     string `json:"expense,omitempty"`
+	Category    string `json:"category"`
+	Description string `json:"description,omitempty"`
+}
+
+// headerAliases are the column titles recognized when a field is not mapped.
+var headerAliases = map[string][]string{
+	"date":        {"tanggal", "tgl", "date", "tanggal transaksi"},
+	"type":        {"jenis", "tipe", "type", "jenis transaksi"},
+	"amount":      {"jumlah", "nominal", "amount", "nilai"},
+	"income":      {"pemasukan", "masuk", "debit", "penerimaan", "income"},
+	"expense":     {"pengeluaran", "keluar", "kredit", "expense"},
+	"category":    {"kategori", "category", "pos"},
+	"description": {"keterangan", "uraian", "deskripsi", "description", "catatan"},
+}
+
+type Options struct {
+	Mapping Mapping
+	// DateFormat is a Go time layout. When empty the common Indonesian
+	// formats are tried: 31/12/2024, 31-12-2024, 2024-12-31, 31 Desember
+	// 2024 and spreadsheet serial numbers.
+	DateFormat string
+	Location   *time.Location
+	// CreateCategories adds categories that do not exist yet instead of
+	// rejecting the rows that use them.
+	CreateCategories bool
+	// ClosedPeriods rejects rows dated in a locked period.
+	ClosedPeriods []domain.ClosedPeriod
+}
+
+// RowError lists what is wrong with one line of the file. Line numbers
+// count the header as line 1.
+type RowError struct {
+	Line   int                     `json:"line"`
+	Errors domain.ValidationErrors `json:"errors"`
+}
+
+// Job is one import: its preview and, once committed, the transactions it
+// created. ID is assigned by Commit, so a preview has none.
+type Job struct {
+	ID            string               `json:"id,omitempty"`
+	Filename      string               `json:"filename"`
+	Format        string               `json:"format"`
+	Mapping       Mapping              `json:"mapping"`
+	TotalRows     int                  `json:"total_rows"`
+	ValidRows     int                  `json:"valid_rows"`
+	InvalidRows   int                  `json:"invalid_rows"`
+	TotalIncome   domain.Money         `json:"total_income"`
+	TotalExpense  domain.Money         `json:"total_expense"`
+	Errors        []RowError           `json:"errors"`
+	NewCategories []domain.Category    `json:"new_categories"`
+	Sample        []domain.Transaction `json:"sample"`
+	Committed     bool                 `json:"committed"`
+
+	transactions []domain.Transaction
+	lines        []int
+}
+
+// Parse maps and validates rows, the first of which must be the header.
+// Nothing is written; the result is the dry-run preview.
+func Parse(sheet *Sheet, filename, format string, categories []domain.Category, opts Options) (*Job, error) {
+	rows := sheet.Rows
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	cols, mapping, err := resolveColumns(rows[0], opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		Filename:      filename,
+		Format:        format,
+		Mapping:       mapping,
+		Errors:        []RowError{},
+		NewCategories: []domain.Category{},
+		Sample:        []domain.Transaction{},
+	}
+	now := time.Now()
+	known := append([]domain.Category{}, categories...)
+
+	for i, row := range rows[1:] {
+		line := i + 2
+		if blank(row) {
+			continue
+		}
+		job.TotalRows++
+
+		tx, errs := parseRow(row, cols, sheet.Numeric(i+1, cols.date), now, opts)
+		if len(errs) == 0 {
+			category, err := domain.ResolveCategory(known, "", tx.Category, tx.Type)
+			switch {
+			case err == nil:
+				tx.CategoryID, tx.Category = category.ID, category.Name
+			case errors.Is(err, domain.ErrCategoryNotFound) && opts.CreateCategories:
+				c := domain.Category{
+					ID:        domain.NewID(),
+					Kind:      tx.Type,
+					Name:      tx.Category,
+					Active:    true,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				c.Code = domain.UniqueCategoryCode(known, domain.CategoryCode(c.Name))
+				if err := domain.ValidateCategory(c, known); err != nil {
+					errs.Add("category", "invalid", fmt.Sprintf("%s: %v", tx.Category, err))
+					break
+				}
+				known = append(known, c)
+				job.NewCategories = append(job.NewCategories, c)
+				tx.CategoryID = c.ID
+			default:
+				errs.Add("category", "invalid", fmt.Sprintf("%s: %v", tx.Category, err))
+			}
+		}
+		if len(errs) > 0 {
+			job.InvalidRows++
+			job.Errors = append(job.Errors, RowError{Line: line, Errors: errs})
+			continue
+		}
+
+		job.ValidRows++
+		if tx.Type == "income" {
+			job.TotalIncome += tx.Amount
+		} else {
+			job.TotalExpense += tx.Amount
+		}
+		job.transactions = append(job.transactions, tx)
+		job.lines = append(job.lines, line)
+		if len(job.Sample) < MaxSample {
+			job.Sample = append(job.Sample, tx)
+		}
+	}
+	return job, nil
+}
+
+// Commit writes the job's categories and transactions through t, each with
+// its own audit entry pointing at the job, followed by one entry for the
+// job itself. Run it inside Store.Update so the import is all or nothing.
+func (job *Job) Commit(t domain.Repositories, a domain.Actor, now time.Time) error {
+	if job.InvalidRows > 0 {
+		return ErrInvalidRows
+	}
+	if job.ValidRows == 0 {
+		return errors.New("nothing to import")
+	}
+	job.ID = domain.NewID()
+
+	for _, c := range job.NewCategories {
+		if err := t.InsertCategory(c); err != nil {
+			return err
+		}
+		entry := domain.AuditLog{
+			EntityType: "category",
+			EntityID:   c.ID,
+			Action:     "create",
+			Note:       fmt.Sprintf("Created category %s (%s) for import %s", c.Name, c.Kind, job.ID),
+			After:      domain.AuditState(c),
+			ImportJob:  job.ID,
+			CreatedAt:  now,
+		}
+		entry.Stamp(a)
+		if err := t.InsertAuditLog(entry); err != nil {
+			return err
+		}
+	}
+
+	for i, tx := range job.transactions {
+		tx.ID = domain.NewID()
+		tx.CreatedAt = now
+		tx.CreatedBy = a.UserID
+		tx.CreatedByUsername = a.Username
+		tx.ImportJob = job.ID
+		if err := t.InsertTransaction(tx); err != nil {
+			return err
+		}
+		entry := domain.AuditLog{
+			EntityType: "transaction",
+			EntityID:   tx.ID,
+			Action:     "create",
+			Note:       fmt.Sprintf("Imported from %s line %d (Amount: %s)", job.Filename, job.lines[i], tx.Amount),
+			After:      domain.AuditState(tx),
+			ImportJob:  job.ID,
+			CreatedAt:  now,
+		}
+		entry.Stamp(a)
+		if err := t.InsertAuditLog(entry); err != nil {
+			return err
+		}
+	}
+
+	entry := domain.AuditLog{
+		EntityType: "import",
+		EntityID:   job.ID,
+		Action:     "import",
+		Note: fmt.Sprintf("Imported %d transactions from %s (income %s, expense %s)",
+			job.ValidRows, job.Filename, job.TotalIncome, job.TotalExpense),
+		ImportJob: job.ID,
+		CreatedAt: now,
+	}
+	entry.Stamp(a)
+	if err := t.InsertAuditLog(entry); err != nil {
+		return err
+	}
+	job.Committed = true
+	return nil
+}
+
+type columns struct {
+	date, typ, amount, income, expense, category, description int
+}
+
+func resolveColumns(header []string, m Mapping) (columns, Mapping, error) {
+	titles := make([]string, len(header))
+	for i, h := range header {
+		titles[i] = strings.ToLower(domain.NormalizeText(strings.TrimPrefix(h, "\ufeff")))
+	}
+
+	var errs domain.ValidationErrors
+	find := func(field, spec string) (int, string) {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			if n, err := strconv.Atoi(spec); err == nil {
+				if n < 1 || n > len(header) {
+					errs.Add(field, "invalid", fmt.Sprintf("column %d does not exist", n))
+					return -1, ""
+				}
+				return n - 1, spec
+			}
+			for i, t := range titles {
+				if t == strings.ToLower(domain.NormalizeText(spec)) {
+					return i, spec
+				}
+			}
+			errs.Add(field, "invalid", fmt.Sprintf("column %q not found in header", spec))
+			return -1, ""
+		}
+		for _, alias := range headerAliases[field] {
+			for i, t := range titles {
+				if t == alias {
+					return i, header[i]
+				}
+			}
+		}
+		return -1, ""
+	}
+
+	var c columns
+	c.date, m.Date = find("date", m.Date)
+	c.typ, m.Type = find("type", m.Type)
+	c.amount, m.Amount = find("amount", m.Amount)
+	c.income, m.Income = find("income", m.Income)
+	c.expense, m.Expense = find("expense", m.Expense)
+	c.category, m.Category = find("category", m.Category)
+	c.description, m.Description = find("description", m.Description)
+
+	if c.date < 0 && !hasField(errs, "date") {
+		errs.Add("date", "required", "no date column; map one explicitly")
+	}
+	if c.category < 0 && !hasField(errs, "category") {
+		errs.Add("category", "required", "no category column; map one explicitly")
+	}
+	if c.amount < 0 && c.income < 0 && c.expense < 0 && !hasField(errs, "amount") {
+		errs.Add("amount", "required", "no amount column, or income and expense columns; map them explicitly")
+	}
+	if len(errs) > 0 {
+		return c, m, errs
+	}
+	return c, m, nil
+}
+
+func hasField(errs domain.ValidationErrors, field string) bool {
+	for _, e := range errs {
+		if e.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRow maps one line. numericDate tells whether the date cell was
+// stored as a number, that is, as a spreadsheet serial date. Future dates
+// are rejected as for manual entries, but the backdating window does not
+// apply: an import usually brings in an old cash book.
+func parseRow(row []string, c columns, numericDate bool, now time.Time, opts Options) (domain.Transaction, domain.ValidationErrors) {
+	var errs domain.ValidationErrors
+	cell := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	date, err := parseDate(cell(c.date), opts.DateFormat, numericDate, opts.Location)
+	if err != nil {
+		errs.Add("date", "invalid", err.Error())
+	} else if len(domain.AppSettings{}.CheckTransactionDate(date, now, opts.Location)) > 0 {
+		errs.Add("date", "future", "date cannot be in the future")
+	} else if p, ok := domain.LockedPeriod(opts.ClosedPeriods, date); ok {
+		errs.Add("date", "closed_period", fmt.Sprintf("period %s is closed", p.Label))
+	}
+
+	in := domain.TransactionInput{
+		Category:    unguard(cell(c.category)),
+		Description: unguard(cell(c.description)),
+	}
+	if c.typ >= 0 {
+		if t, ok := parseType(cell(c.typ)); ok {
+			in.Type = t
+		} else if cell(c.typ) != "" {
+			errs.Add("type", "invalid", fmt.Sprintf("unknown type %q", cell(c.typ)))
+		}
+	}
+
+	amount := func(field string, i int) (domain.Money, bool) {
+		v := cell(i)
+		if v == "" || v == "-" {
+			return 0, false
+		}
+		neg := false
+		if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+			neg, v = true, v[1:len(v)-1]
+		}
+		m, err := domain.ParseMoney(v)
+		if err != nil {
+			errs.Add(field, "invalid", fmt.Sprintf("%q is not an amount", cell(i)))
+			return 0, false
+		}
+		if neg {
+			m = -m
+		}
+		return m, m != 0
+	}
+
+	switch {
+	case c.amount >= 0:
+		if m, ok := amount("amount", c.amount); ok {
+			if in.Type == "" && c.typ < 0 {
+				// A signed amount column: negatives are expenses.
+				in.Type = "income"
+				if m < 0 {
+					in.Type, m = "expense", -m
+				}
+			}
+			in.Amount = &m
+		}
+	default:
+		income, hasIncome := amount("income", c.income)
+		expense, hasExpense := amount("expense", c.expense)
+		switch {
+		case hasIncome && hasExpense:
+			errs.Add("amount", "invalid", "row has both an income and an expense amount")
+		case hasIncome:
+			in.Type, in.Amount = "income", &income
+		case hasExpense:
+			in.Type, in.Amount = "expense", &expense
+		}
+	}
+
+	in.Normalize()
+	for _, e := range in.Validate(false) {
+		// Type and amount both come from the amount columns; a bad amount
+		// is reported once, not again as missing.
+		if (e.Field == "type" || e.Field == "amount") && hasField(errs, "amount") {
+			continue
+		}
+		errs = append(errs, e)
+	}
+	if len(errs) > 0 {
+		return domain.Transaction{}, errs
+	}
+	return domain.Transaction{
+		Type:            in.Type,
+		Amount:          *in.Amount,
+		Category:        in.Category,
+		Description:     in.Description,
+		TransactionDate: &date,
+	}, nil
+}
+
+// unguard drops the apostrophe the CSV export puts before text that a
+// spreadsheet would read as a formula, so exports import back unchanged.
+func unguard(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func parseType(s string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "income", "pemasukan", "masuk", "debit", "in", "penerimaan", "+":
+		return "income", true
+	case "expense", "pengeluaran", "keluar", "kredit", "out", "-":
+		return "expense", true
+	}
+	return "", false
+}
+
+var dateLayouts = []string{
+	"02/01/2006", "2/1/2006", "02-01-2006", "2-1-2006", "02.01.2006",
+	"2006-01-02", "2006/01/02",
+	"02/01/2006 15:04", "02/01/2006 15:04:05", "2006-01-02 15:04", "2006-01-02 15:04:05",
+	time.RFC3339,
+	"02/01/06", "2/1/06",
+}
+
+var monthAliases = map[string]string{
+	"januari": "01", "jan": "01",
+	"februari": "02", "feb": "02", "peb": "02",
+	"maret": "03", "mar": "03",
+	"april": "04", "apr": "04",
+	"mei":  "05",
+	"juni": "06", "jun": "06",
+	"juli": "07", "jul": "07",
+	"agustus": "08", "agu": "08", "agt": "08", "ags": "08",
+	"september": "09", "sep": "09", "sept": "09",
+	"oktober": "10", "okt": "10",
+	"november": "11", "nov": "11", "nop": "11",
+	"desember": "12", "des": "12",
+}
+
+// excelEpoch is day zero of spreadsheet serial dates (with the 1900 leap
+// year bug folded in).
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Serial dates are only taken between 1950 and 2100. Smaller numbers in a
+// date column are far more likely years, such as 2024, than dates in the
+// first half of the last century.
+var (
+	minSerialDate = time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC).Sub(excelEpoch).Hours() / 24
+	maxSerialDate = time.Date(2101, 1, 1, 0, 0, 0, 0, time.UTC).Sub(excelEpoch).Hours() / 24
+)
+
+// parseDate reads a date cell. Spreadsheet serial numbers are accepted only
+// from cells stored as numbers.
+func parseDate(s, layout string, numeric bool, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("date is required")
+	}
+	if layout != "" {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q does not match date format %q", s, layout)
+		}
+		return t, nil
+	}
+	if serial, err := strconv.ParseFloat(s, 64); err == nil && numeric {
+		if serial < minSerialDate || serial >= maxSerialDate {
+			return time.Time{}, fmt.Errorf("%s is not a date between 1950 and 2100", s)
+		}
+		days := math.Floor(serial)
+		d := excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration((serial - days) * 24 * float64(time.Hour)))
+		return time.Date(d.Year(), d.Month(), d.Day(), d.Hour(), d.Minute(), 0, 0, loc), nil
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	// "31 Desember 2024" or "31 Des 2024"
+	if f := strings.Fields(strings.ReplaceAll(s, "-", " ")); len(f) == 3 {
+		if m, ok := monthAliases[strings.ToLower(f[1])]; ok {
+			if t, err := time.ParseInLocation("2 01 2006", f[0]+" "+m+" "+f[2], loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a recognized date", s)
+}
+
+func blank(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
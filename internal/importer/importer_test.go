@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"audit-sendiri/internal/db"
+	"audit-sendiri/internal/domain"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	loc := time.UTC
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, loc) }
+	tests := []struct {
+		in      string
+		layout  string
+		numeric bool
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "31/12/2024", want: day(2024, 12, 31)},
+		{in: "31-12-2024", want: day(2024, 12, 31)},
+		{in: "2024-12-31", want: day(2024, 12, 31)},
+		{in: "31 Desember 2024", want: day(2024, 12, 31)},
+		{in: "5 Agt 2024", want: day(2024, 8, 5)},
+		{in: "12/31/2024", layout: "01/02/2006", want: day(2024, 12, 31)},
+		{in: "45292", numeric: true, want: day(2024, 1, 1)},
+		{in: "45292.5", numeric: true, want: time.Date(2024, 1, 1, 12, 0, 0, 0, loc)},
+		// Serial numbers only count from numeric spreadsheet cells.
+		{in: "45292", wantErr: true},
+		// A year is not a serial date from 1905.
+		{in: "2024", numeric: true, wantErr: true},
+		{in: "2024", wantErr: true},
+		// Far beyond 2100; used to overflow time.Duration.
+		{in: "2958465", numeric: true, wantErr: true},
+		{in: "-1", numeric: true, wantErr: true},
+		{in: "", wantErr: true},
+		{in: "kemarin", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.in, tt.layout, tt.numeric, loc)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDate(%q, numeric=%t) = %v, want error", tt.in, tt.numeric, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q, numeric=%t) = %v, %v; want %v", tt.in, tt.numeric, got, err, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	categories := []domain.Category{
+		{ID: "c1", Kind: "income", Code: "IURAN", Name: "Iuran Warga", Active: true},
+		{ID: "c2", Kind: "expense", Code: "KEBERSIHAN", Name: "Kebersihan", Active: true},
+	}
+	sheet := &Sheet{Rows: [][]string{
+		{"Tanggal", "Keterangan", "Kategori", "Pemasukan", "Pengeluaran"},
+		{"01/01/2024", "Iuran Januari", "Iuran Warga", "1.250.000", ""},
+		{"05/01/2024", "Beli sapu", "Kebersihan", "", "35.000,50"},
+		{"", "", "", "", ""},
+		{"bukan tanggal", "Rusak", "Iuran Warga", "1000", ""},
+		{"06/01/2024", "Dua jumlah", "Kebersihan", "1000", "2000"},
+		{"07/01/2024", "Kategori baru", "Listrik", "", "50000"},
+	}}
+	job, err := Parse(sheet, "kas.csv", FormatCSV, categories, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.TotalRows != 5 || job.ValidRows != 2 || job.InvalidRows != 3 {
+		t.Fatalf("rows = %d total, %d valid, %d invalid; want 5, 2, 3", job.TotalRows, job.ValidRows, job.InvalidRows)
+	}
+	if job.TotalIncome != domain.Money(125000000) || job.TotalExpense != domain.Money(3500050) {
+		t.Errorf("totals = %s, %s", job.TotalIncome, job.TotalExpense)
+	}
+	lines := []int{}
+	for _, e := range job.Errors {
+		lines = append(lines, e.Line)
+	}
+	if len(lines) != 3 || lines[0] != 5 || lines[1] != 6 || lines[2] != 7 {
+		t.Errorf("error lines = %v, want [5 6 7]", lines)
+	}
+
+	job, err = Parse(sheet, "kas.csv", FormatCSV, categories, Options{CreateCategories: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.NewCategories) != 1 || job.NewCategories[0].Name != "Listrik" {
+		t.Errorf("new categories = %v, want Listrik", job.NewCategories)
+	}
+
+	closed := []domain.ClosedPeriod{{
+		Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Label: "Januari 2024",
+	}}
+	job, err = Parse(sheet, "kas.csv", FormatCSV, categories, Options{CreateCategories: true, ClosedPeriods: closed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ValidRows != 0 {
+		t.Errorf("valid rows in a closed period = %d, want 0", job.ValidRows)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("02/01/2006")
+	dated := &Sheet{Rows: [][]string{
+		{"Tanggal", "Kategori", "Pemasukan"},
+		{"01/01/2000", "Iuran Warga", "1000"},
+		{tomorrow, "Iuran Warga", "1000"},
+	}}
+	job, err = Parse(dated, "kas.csv", FormatCSV, categories, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ValidRows != 1 || len(job.Errors) != 1 || job.Errors[0].Line != 3 || job.Errors[0].Errors[0].Code != "future" {
+		t.Errorf("future date: valid %d, errors %+v; want line 3 rejected as future", job.ValidRows, job.Errors)
+	}
+}
+
+func TestJobCommit(t *testing.T) {
+	store := db.NewMemoryStore()
+	categories := []domain.Category{{ID: "c1", Kind: "income", Code: "IURAN", Name: "Iuran Warga", Active: true}}
+	sheet := &Sheet{Rows: [][]string{
+		{"Tanggal", "Keterangan", "Kategori", "Pemasukan", "Pengeluaran"},
+		{"01/01/2024", "Iuran Januari", "Iuran Warga", "1.250.000", ""},
+		{"02/01/2024", "Token listrik", "Listrik", "", "100.000"},
+	}}
+	job, err := Parse(sheet, "kas.csv", FormatCSV, categories, Options{CreateCategories: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != "" {
+		t.Errorf("preview has id %q, want none until commit", job.ID)
+	}
+	now := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	err = store.Update(func(tx domain.Repositories) error {
+		return job.Commit(tx, domain.Actor{UserID: "u1", Username: "bendahara"}, now)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !job.Committed || job.ID == "" {
+		t.Error("job not marked committed")
+	}
+
+	txs := store.Transactions()
+	if len(txs) != 2 {
+		t.Fatalf("%d transactions, want 2", len(txs))
+	}
+	for _, tx := range txs {
+		if tx.ImportJob != job.ID || tx.CreatedBy != "u1" || !tx.CreatedAt.Equal(now) {
+			t.Errorf("transaction %+v not stamped with the job and actor", tx)
+		}
+	}
+	if len(store.Categories()) != 1 || store.Categories()[0].Name != "Listrik" {
+		t.Errorf("categories = %v, want the new Listrik category", store.Categories())
+	}
+
+	// A category, two transactions and the import itself, all chained.
+	logs := store.AuditLogs()
+	if len(logs) != 4 || logs[3].Action != "import" {
+		t.Fatalf("%d audit entries, want 4 ending with the import", len(logs))
+	}
+	if r := domain.VerifyAuditChain(logs); !r.Valid {
+		t.Errorf("audit chain broken: %s", r.Reason)
+	}
+
+	bad := &Sheet{Rows: [][]string{sheet.Rows[0], {"bukan tanggal", "x", "Iuran Warga", "1", ""}}}
+	job, err = Parse(bad, "kas.csv", FormatCSV, categories, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Update(func(tx domain.Repositories) error {
+		return job.Commit(tx, domain.Actor{UserID: "u1"}, now)
+	})
+	if !errors.Is(err, ErrInvalidRows) || len(store.Transactions()) != 2 {
+		t.Errorf("commit with invalid rows = %v, %d transactions", err, len(store.Transactions()))
+	}
+}
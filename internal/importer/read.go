@@ -0,0 +1,263 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Formats accepted by ReadRows.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported file format; use .csv or .xlsx")
+
+// FormatOf picks the format from a file name.
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv", ".txt":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Sheet is the content of an uploaded file, every cell as text.
+type Sheet struct {
+	Rows [][]string
+	// numeric marks the cells a spreadsheet stored as numbers, by row and
+	// column. CSV cells are all text.
+	numeric map[[2]int]bool
+}
+
+// Numeric reports whether the cell at row and col was stored as a number.
+func (s *Sheet) Numeric(row, col int) bool {
+	return s.numeric[[2]int{row, col}]
+}
+
+// ReadRows returns every row of the file. For CSV a zero delimiter is
+// detected from the first line; XLSX reads the first worksheet.
+func ReadRows(data []byte, format string, delimiter rune) (*Sheet, error) {
+	switch format {
+	case FormatCSV:
+		rows, err := readCSV(data, delimiter)
+		if err != nil {
+			return nil, err
+		}
+		return &Sheet{Rows: rows}, nil
+	case FormatXLSX:
+		return readXLSX(data)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func readCSV(data []byte, delimiter rune) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if delimiter == 0 {
+		delimiter = detectDelimiter(data)
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r.ReadAll()
+}
+
+// detectDelimiter picks the most frequent of comma, semicolon and tab in the
+// first line. Spreadsheets set to Indonesian locale export with semicolons.
+func detectDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	best, count := ',', bytes.Count(line, []byte(","))
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(d))); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the first worksheet. Only cell values are used: numbers
+// come back as plain decimals and dates as Excel serial day numbers, which
+// parseDate understands for numeric cells.
+func readXLSX(data []byte) (*Sheet, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("reading xlsx: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	decode := func(name string, v interface{}) (bool, error) {
+		f, ok := files[name]
+		if !ok {
+			return false, nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return true, err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(rc).Decode(v); err != nil && err != io.EOF {
+			return true, fmt.Errorf("reading xlsx %s: %w", name, err)
+		}
+		return true, nil
+	}
+
+	sheetPath := "xl/worksheets/sheet1.xml"
+	var wb xlsxWorkbook
+	var rels xlsxRels
+	if ok, err := decode("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	} else if ok && len(wb.Sheets) > 0 {
+		if _, err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+			return nil, err
+		}
+		for _, r := range rels.Rels {
+			if r.ID == wb.Sheets[0].RID {
+				target := strings.TrimPrefix(r.Target, "/")
+				if !strings.HasPrefix(target, "xl/") {
+					target = "xl/" + target
+				}
+				sheetPath = target
+			}
+		}
+	}
+
+	var shared struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if _, err := decode("xl/sharedStrings.xml", &shared); err != nil {
+		return nil, err
+	}
+
+	var sheet xlsxSheet
+	if ok, err := decode(sheetPath, &sheet); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("reading xlsx: no worksheet found")
+	}
+
+	result := &Sheet{Rows: make([][]string, 0, len(sheet.Rows)), numeric: map[[2]int]bool{}}
+	for _, row := range sheet.Rows {
+		var out []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				var err error
+				if col, err = columnIndex(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(out) <= col {
+				out = append(out, "")
+			}
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(c.Value)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("reading xlsx: bad shared string in %s", c.Ref)
+				}
+				out[col] = shared.Items[n].String()
+			case "inlineStr":
+				out[col] = c.Inline.String()
+			case "", "n":
+				out[col] = xlsxNumber(c.Value)
+				result.numeric[[2]int{len(result.Rows), col}] = c.Value != ""
+			default:
+				out[col] = c.Value
+			}
+		}
+		result.Rows = append(result.Rows, out)
+	}
+	return result, nil
+}
+
+// maxXLSXColumns is the last column a worksheet can have, XFD.
+const maxXLSXColumns = 16384
+
+// columnIndex converts the letters of a cell reference such as "AB12" to a
+// zero-based column.
+func columnIndex(ref string) (int, error) {
+	n, letters := 0, 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		letters++
+		n = n*26 + int(r-'A'+1)
+		if letters > 3 || n > maxXLSXColumns {
+			return 0, fmt.Errorf("reading xlsx: bad cell reference %q", ref)
+		}
+	}
+	if letters == 0 {
+		return 0, fmt.Errorf("reading xlsx: bad cell reference %q", ref)
+	}
+	return n - 1, nil
+}
+
+// xlsxNumber drops the binary floating point noise spreadsheets store, such
+// as 1250000.0000000002, by rounding to the sen.
+func xlsxNumber(v string) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{ref: "A1", want: 0},
+		{ref: "Z9", want: 25},
+		{ref: "AA1", want: 26},
+		{ref: "AB12", want: 27},
+		{ref: "XFD1", want: 16383},
+		{ref: "XFE1", wantErr: true},
+		{ref: "ZZZZZZ1", wantErr: true},
+		{ref: "AAAAAAAAAAAAAAA1", wantErr: true},
+		{ref: "12", wantErr: true},
+		{ref: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := columnIndex(tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("columnIndex(%q) = %d, want error", tt.ref, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("columnIndex(%q) = %d, %v; want %d", tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func xlsxFile(t *testing.T, sheet string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(sheet)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSXRejectsBadCellReference(t *testing.T) {
+	for _, ref := range []string{"AAAAAAAAAAAAAAA1", "ZZZZZZ1", "1"} {
+		data := xlsxFile(t, `<worksheet><sheetData><row><c r="`+ref+`" t="inlineStr"><is><t>x</t></is></c></row></sheetData></worksheet>`)
+		_, err := ReadRows(data, FormatXLSX, 0)
+		if err == nil || !strings.Contains(err.Error(), "bad cell reference") {
+			t.Errorf("ref %s: err = %v, want bad cell reference", ref, err)
+		}
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := xlsxFile(t, `<worksheet><sheetData>`+
+		`<row><c r="A1" t="inlineStr"><is><t>Tanggal</t></is></c><c r="C1" t="inlineStr"><is><t>Jumlah</t></is></c></row>`+
+		`<row><c r="A2"><v>45292</v></c><c r="C2"><v>1250000.0000000002</v></c></row>`+
+		`</sheetData></worksheet>`)
+	sheet, err := ReadRows(data, FormatXLSX, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := sheet.Rows
+	want := [][]string{{"Tanggal", "", "Jumlah"}, {"45292", "", "1250000"}}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+	if !sheet.Numeric(1, 0) || sheet.Numeric(0, 0) || sheet.Numeric(1, 1) {
+		t.Errorf("numeric cells = %v, want only A2 and C2", sheet.numeric)
+	}
+}
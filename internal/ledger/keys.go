@@ -0,0 +1,108 @@
+package ledger
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const keyFileName = "ledger.key"
+
+// KeyStore holds the instance's Ed25519 signing key in the data directory,
+// next to data.sawit. The key is created once and never rotated implicitly:
+// residents pin its public key to recognise exports from their RT.
+type KeyStore struct {
+	path string
+
+	mu  sync.Mutex
+	key ed25519.PrivateKey
+}
+
+func NewKeyStore(dataDir string) *KeyStore {
+	return &KeyStore{path: filepath.Join(dataDir, keyFileName)}
+}
+
+// LoadOrGenerate returns the signing key, generating and storing a new one
+// if none exists yet.
+func (ks *KeyStore) LoadOrGenerate() (ed25519.PrivateKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.key != nil {
+		return ks.key, nil
+	}
+
+	data, err := os.ReadFile(ks.path)
+	if err == nil {
+		key, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", ks.path, err)
+		}
+		ks.key = key
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	// O_EXCL so two racing callers can never overwrite each other's key.
+	f, err := os.OpenFile(ks.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(pemBytes); err != nil {
+		f.Close()
+		os.Remove(ks.path)
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	ks.key = key
+	return key, nil
+}
+
+func parsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("no PEM private key found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("key is not an Ed25519 key")
+	}
+	return key, nil
+}
+
+// KeyID is a short fingerprint of a public key that residents can compare
+// by eye or read out at a meeting.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,152 @@
+package ledger
+
+import (
+	"audit-sendiri/internal/domain"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	FormatVersion = 1
+	Algorithm     = "ed25519"
+)
+
+// Ledger is the signed content of a public export.
+type Ledger struct {
+	Version      int                  `json:"version"`
+	Issuer       domain.AppSettings   `json:"issuer"`
+	GeneratedAt  time.Time            `json:"generated_at"`
+	Transactions []domain.Transaction `json:"transactions"`
+	TotalIncome  domain.Money         `json:"total_income"`
+	TotalExpense domain.Money         `json:"total_expense"`
+	Balance      domain.Money         `json:"balance"`
+	// AuditChainHead is the hash of the newest audit log entry, tying the
+	// export to one exact point in the tamper-evident history.
+	AuditChainHead string `json:"audit_chain_head"`
+	AuditEntries   int    `json:"audit_entries"`
+}
+
+// SignedLedger is the file residents download. Signature covers the
+// compact JSON encoding of Ledger without HTML escaping, so changing only
+// the whitespace between tokens does not invalidate it. Tools that also
+// rewrite strings or numbers, such as jq or Python's json module turning
+// 35000.50 into 35000.5, do.
+type SignedLedger struct {
+	Ledger    json.RawMessage `json:"ledger"`
+	Algorithm string          `json:"algorithm"`
+	PublicKey string          `json:"public_key"`
+	KeyID     string          `json:"key_id"`
+	Signature string          `json:"signature"`
+}
+
+// Build assembles a ledger from the active transactions and audit log.
+func Build(settings domain.AppSettings, transactions []domain.Transaction, auditLogs []domain.AuditLog, now time.Time) Ledger {
+	l := Ledger{
+		Version:      FormatVersion,
+		Issuer:       settings,
+		GeneratedAt:  now,
+		Transactions: []domain.Transaction{},
+		AuditEntries: len(auditLogs),
+	}
+	for _, tx := range transactions {
+		if tx.DeletedAt != nil {
+			continue
+		}
+		l.Transactions = append(l.Transactions, tx)
+	}
+	l.TotalIncome, l.TotalExpense = totals(l.Transactions)
+	l.Balance = l.TotalIncome - l.TotalExpense
+	if n := len(auditLogs); n > 0 {
+		l.AuditChainHead = auditLogs[n-1].Hash
+	}
+	return l
+}
+
+func totals(transactions []domain.Transaction) (income, expense domain.Money) {
+	for _, tx := range transactions {
+		switch tx.Type {
+		case "income":
+			income += tx.Amount
+		case "expense":
+			expense += tx.Amount
+		}
+	}
+	return income, expense
+}
+
+func Sign(l Ledger, key ed25519.PrivateKey) (SignedLedger, error) {
+	payload, err := Encode(l)
+	if err != nil {
+		return SignedLedger{}, err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	return SignedLedger{
+		Ledger:    payload,
+		Algorithm: Algorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		KeyID:     KeyID(pub),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}, nil
+}
+
+// Encode is json.Marshal without HTML escaping. Write the signed file with
+// it too: json.Marshal would escape <, > and & inside the signed ledger
+// and break the signature.
+func Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Verify checks the signature of an exported file and that its totals add
+// up. If trusted is non-nil the file must also be signed by that key;
+// otherwise the embedded key is used and the caller should compare KeyID
+// against the one their RT published.
+func Verify(file []byte, trusted ed25519.PublicKey) (*SignedLedger, *Ledger, error) {
+	var signed SignedLedger
+	if err := json.Unmarshal(file, &signed); err != nil {
+		return nil, nil, fmt.Errorf("not a ledger export: %w", err)
+	}
+	if signed.Algorithm != Algorithm {
+		return nil, nil, fmt.Errorf("unsupported signature algorithm %q", signed.Algorithm)
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(signed.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, nil, errors.New("invalid public key")
+	}
+	if trusted != nil && !bytes.Equal(pub, trusted) {
+		return nil, nil, fmt.Errorf("signed by key %s, expected %s", KeyID(pub), KeyID(trusted))
+	}
+	sig, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, nil, errors.New("invalid signature encoding")
+	}
+
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, signed.Ledger); err != nil {
+		return nil, nil, fmt.Errorf("invalid ledger content: %w", err)
+	}
+	if !ed25519.Verify(pub, payload.Bytes(), sig) {
+		return nil, nil, errors.New("signature does not match ledger content")
+	}
+
+	var l Ledger
+	if err := json.Unmarshal(payload.Bytes(), &l); err != nil {
+		return nil, nil, err
+	}
+	income, expense := totals(l.Transactions)
+	if income != l.TotalIncome || expense != l.TotalExpense || income-expense != l.Balance {
+		return nil, nil, errors.New("signed totals do not match the signed transactions")
+	}
+	return &signed, &l, nil
+}
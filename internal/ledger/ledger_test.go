@@ -0,0 +1,44 @@
+package ledger
+
+import (
+	"audit-sendiri/internal/domain"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestVerifyAfterReindent(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	txs := []domain.Transaction{{ID: "t1", Type: "income", Amount: domain.Rupiah(1000), Category: "Iuran", Description: "Iuran <RT 01> & RW", CreatedAt: now}}
+	signed, err := Sign(Build(domain.AppSettings{RTName: "01"}, txs, nil, now), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := Encode(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, file, "", "    "); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range [][]byte{file, indented.Bytes()} {
+		if _, l, err := Verify(f, key.Public().(ed25519.PublicKey)); err != nil {
+			t.Errorf("verify: %v", err)
+		} else if l.Transactions[0].Description != txs[0].Description {
+			t.Errorf("description = %q", l.Transactions[0].Description)
+		}
+	}
+
+	tampered := bytes.Replace(file, []byte("1000"), []byte("2000"), 1)
+	if _, _, err := Verify(tampered, nil); err == nil {
+		t.Error("tampered file verified")
+	}
+}
@@ -0,0 +1,322 @@
+// Package query turns listing parameters (from, to, type, category, q, sort
+// and so on) into AQL and filters. The API reads them from the request URL
+// and auditctl from its -filter flag, so both select the same records.
+package query
+
+import (
+	"audit-sendiri/internal/db/aql"
+	"audit-sendiri/internal/domain"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+	// DefaultTimezone interprets date-only filters when no tz is given.
+	DefaultTimezone = "Asia/Jakarta"
+)
+
+// Getter reads one parameter, falling back to defaultValue when it is
+// empty. fiber's Ctx.Query has this signature.
+type Getter func(key string, defaultValue ...string) string
+
+// Values adapts parsed URL query values, e.g. from auditctl's -filter flag.
+func Values(v url.Values) Getter {
+	return func(key string, defaultValue ...string) string {
+		if s := v.Get(key); s != "" || len(defaultValue) == 0 {
+			return s
+		}
+		return defaultValue[0]
+	}
+}
+
+var transactionSortFields = map[string]bool{
+	"transaction_date": true,
+	"created_at":       true,
+	"amount":           true,
+	"type":             true,
+	"category":         true,
+	"description":      true,
+}
+
+// DateRange is a [From, To) interval read from the from, to and tz
+// parameters.
+type DateRange struct {
+	From     *time.Time
+	To       *time.Time
+	Location *time.Location
+}
+
+// Contains reports whether t falls inside the range.
+func (r DateRange) Contains(t time.Time) bool {
+	return (r.From == nil || !t.Before(*r.From)) && (r.To == nil || t.Before(*r.To))
+}
+
+// ParseDateRange reads from, to and tz. Dates without a time are whole days
+// in tz, so a date-only to includes that entire day.
+func ParseDateRange(get Getter, errs *domain.ValidationErrors) DateRange {
+	loc, err := time.LoadLocation(get("tz", DefaultTimezone))
+	if err != nil {
+		errs.Add("tz", "invalid", "tz must be an IANA timezone such as Asia/Jakarta")
+		loc = time.UTC
+	}
+	r := DateRange{Location: loc}
+
+	if v := get("from"); v != "" {
+		if from, _, ok := parseDate(v, loc); ok {
+			r.From = &from
+		} else {
+			errs.Add("from", "invalid", "from must be a date (YYYY-MM-DD) or RFC 3339 time")
+		}
+	}
+	if v := get("to"); v != "" {
+		to, dateOnly, ok := parseDate(v, loc)
+		switch {
+		case !ok:
+			errs.Add("to", "invalid", "to must be a date (YYYY-MM-DD) or RFC 3339 time")
+		case dateOnly:
+			to = to.AddDate(0, 0, 1)
+			r.To = &to
+		default:
+			to = to.Add(time.Nanosecond)
+			r.To = &to
+		}
+	}
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		errs.Add("to", "invalid", "to must not be before from")
+	}
+	return r
+}
+
+// ParseDateRangeOnly is ParseDateRange for outputs that must cover every
+// transaction in the range, such as journals with balance assertions: the
+// other listing filters are rejected instead of ignored.
+func ParseDateRangeOnly(get Getter, errs *domain.ValidationErrors) DateRange {
+	for _, key := range []string{"type", "category", "min_amount", "max_amount", "q", "sort", "order"} {
+		if get(key) != "" {
+			errs.Add(key, "unsupported", key+" cannot be combined with this format; only from, to and tz are allowed")
+		}
+	}
+	return ParseDateRange(get, errs)
+}
+
+// parseDate accepts YYYY-MM-DD, meaning midnight in loc, or an RFC 3339
+// timestamp.
+func parseDate(v string, loc *time.Location) (time.Time, bool, bool) {
+	if t, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+		return t, true, true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, false, true
+	}
+	return time.Time{}, false, false
+}
+
+// TransactionFilter is the AQL condition selecting live transactions.
+type TransactionFilter struct {
+	Range      DateRange
+	conditions []string
+}
+
+// ParseTransactionFilter reads the date range, type, category, min_amount,
+// max_amount and q.
+func ParseTransactionFilter(get Getter, categories []domain.Category, errs *domain.ValidationErrors) TransactionFilter {
+	f := TransactionFilter{conditions: []string{"deleted_at ADALAH KOSONG"}}
+
+	f.Range = ParseDateRange(get, errs)
+	if f.Range.From != nil {
+		f.add("transaction_date >= %s", aql.Quote(f.Range.From.Format(time.RFC3339Nano)))
+	}
+	if f.Range.To != nil {
+		f.add("transaction_date < %s", aql.Quote(f.Range.To.Format(time.RFC3339Nano)))
+	}
+
+	switch v := strings.ToLower(get("type")); v {
+	case "":
+	case "income", "expense":
+		f.add("type = %s", aql.Quote(v))
+	default:
+		errs.Add("type", "invalid", "type must be income or expense")
+	}
+
+	if v := get("category"); v != "" {
+		var ids, names []string
+		for _, ref := range strings.Split(v, ",") {
+			ref = strings.TrimSpace(ref)
+			if ref == "" {
+				continue
+			}
+			if cat, ok := findCategory(categories, ref); ok {
+				ids = append(ids, aql.Quote(cat.ID))
+			} else {
+				names = append(names, aql.Quote(ref))
+			}
+		}
+		var alternatives []string
+		if len(ids) > 0 {
+			alternatives = append(alternatives, "category_id DALAM ("+strings.Join(ids, ", ")+")")
+		}
+		if len(names) > 0 {
+			alternatives = append(alternatives, "category DALAM ("+strings.Join(names, ", ")+")")
+		}
+		if len(alternatives) > 0 {
+			f.add("(%s)", strings.Join(alternatives, " ATAU "))
+		}
+	}
+
+	if v := get("min_amount"); v != "" {
+		if m, err := domain.ParseMoney(v); err != nil {
+			errs.Add("min_amount", "invalid", err.Error())
+		} else {
+			f.add("amount >= %s", m.Decimal())
+		}
+	}
+	if v := get("max_amount"); v != "" {
+		if m, err := domain.ParseMoney(v); err != nil {
+			errs.Add("max_amount", "invalid", err.Error())
+		} else {
+			f.add("amount <= %s", m.Decimal())
+		}
+	}
+
+	if q := domain.NormalizeText(get("q")); q != "" {
+		pattern := aql.Quote("%" + aql.EscapeLike(q) + "%")
+		f.add("(description SEPERTI %s ATAU category SEPERTI %s)", pattern, pattern)
+	}
+
+	return f
+}
+
+func (f *TransactionFilter) add(format string, args ...interface{}) {
+	f.conditions = append(f.conditions, fmt.Sprintf(format, args...))
+}
+
+// Where returns the filter as an AQL condition.
+func (f TransactionFilter) Where() string {
+	return strings.Join(f.conditions, " DAN ")
+}
+
+// ParseTransactionOrder reads sort and order into an AQL ordering, by
+// transaction date unless sort says otherwise and breaking ties by
+// created_at. defaultOrder is used when order is not given.
+func ParseTransactionOrder(get Getter, defaultOrder string, errs *domain.ValidationErrors) string {
+	sortField := get("sort", "transaction_date")
+	if !transactionSortFields[sortField] {
+		errs.Add("sort", "invalid", "sort must be one of transaction_date, created_at, amount, type, category, description")
+	}
+	direction := "TURUN"
+	switch strings.ToLower(get("order", defaultOrder)) {
+	case "desc":
+	case "asc":
+		direction = "NAIK"
+	default:
+		errs.Add("order", "invalid", "order must be asc or desc")
+	}
+
+	order := sortField + " " + direction
+	if sortField != "created_at" {
+		order += ", created_at " + direction
+	}
+	return order
+}
+
+// ParsePage reads page and limit. The offset they give, (page-1)*limit,
+// must not exceed math.MaxInt32.
+func ParsePage(get Getter, errs *domain.ValidationErrors) (int, int) {
+	page, err := strconv.Atoi(get("page", "1"))
+	pageOK := err == nil && page >= 1
+	if !pageOK {
+		errs.Add("page", "invalid", "page must be a positive integer")
+	}
+	limit, err := strconv.Atoi(get("limit", strconv.Itoa(DefaultPageSize)))
+	if err != nil || limit < 1 || limit > MaxPageSize {
+		errs.Add("limit", "invalid", fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+	} else if pageOK && page-1 > math.MaxInt32/limit {
+		errs.Add("page", "too_large", fmt.Sprintf("page must be at most %d for limit %d", math.MaxInt32/limit+1, limit))
+	}
+	return page, limit
+}
+
+// Transactions builds the AQL statement for a transaction listing. With
+// paged set it reads page and limit as well; otherwise every match is
+// selected, oldest first unless order says otherwise.
+func Transactions(get Getter, categories []domain.Category, paged bool) (string, int, int, error) {
+	var errs domain.ValidationErrors
+	filter := ParseTransactionFilter(get, categories, &errs)
+	defaultOrder := "asc"
+	if paged {
+		defaultOrder = "desc"
+	}
+	order := ParseTransactionOrder(get, defaultOrder, &errs)
+	var page, limit int
+	if paged {
+		page, limit = ParsePage(get, &errs)
+	}
+	if len(errs) > 0 {
+		return "", 0, 0, errs
+	}
+
+	stmt := fmt.Sprintf("PANEN * DARI transactions DIMANA %s URUT BERDASARKAN %s", filter.Where(), order)
+	if paged {
+		stmt += fmt.Sprintf(" BATAS %d LEWATI %d", limit, (page-1)*limit)
+	}
+	return stmt, page, limit, nil
+}
+
+func findCategory(categories []domain.Category, ref string) (domain.Category, bool) {
+	for _, cat := range categories {
+		if cat.ID == ref || strings.EqualFold(cat.Code, ref) {
+			return cat, true
+		}
+	}
+	for _, cat := range categories {
+		if domain.CategoryKey(cat.Name) == domain.CategoryKey(ref) {
+			return cat, true
+		}
+	}
+	return domain.Category{}, false
+}
+
+// AuditFilter selects audit log entries by date range, entity_type,
+// entity_id, action and user (ID or username).
+type AuditFilter struct {
+	Range      DateRange
+	EntityType string
+	EntityID   string
+	Action     string
+	User       string
+}
+
+func ParseAuditFilter(get Getter, errs *domain.ValidationErrors) AuditFilter {
+	return AuditFilter{
+		Range:      ParseDateRange(get, errs),
+		EntityType: get("entity_type"),
+		EntityID:   get("entity_id"),
+		Action:     get("action"),
+		User:       get("user"),
+	}
+}
+
+func (f AuditFilter) Match(l domain.AuditLog) bool {
+	return f.Range.Contains(l.CreatedAt) &&
+		(f.EntityType == "" || l.EntityType == f.EntityType) &&
+		(f.EntityID == "" || l.EntityID == f.EntityID) &&
+		(f.Action == "" || l.Action == f.Action) &&
+		(f.User == "" || l.CreatedBy == f.User || l.CreatedByUsername == f.User)
+}
+
+// Filter returns the entries that match, in log order.
+func (f AuditFilter) Filter(logs []domain.AuditLog) []domain.AuditLog {
+	out := []domain.AuditLog{}
+	for _, l := range logs {
+		if f.Match(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
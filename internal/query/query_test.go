@@ -0,0 +1,48 @@
+package query
+
+import (
+	"audit-sendiri/internal/domain"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query     string
+		page      int
+		limit     int
+		badFields string
+	}{
+		{query: "", page: 1, limit: DefaultPageSize},
+		{query: "page=3&limit=20", page: 3, limit: 20},
+		{query: "page=4294968&limit=500", page: 4294968, limit: 500},
+		{query: "page=4294969&limit=500", badFields: "page"},
+		{query: "page=2147483648&limit=1", page: 2147483648, limit: 1},
+		{query: "page=2147483649&limit=1", badFields: "page"},
+		{query: "page=9223372036854775807", badFields: "page"},
+		{query: "page=99999999999999999999", badFields: "page"},
+		{query: "page=0", badFields: "page"},
+		{query: "page=x&limit=0", badFields: "page limit"},
+		{query: "page=9223372036854775807&limit=501", badFields: "limit"},
+	}
+	for _, tt := range tests {
+		v, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs domain.ValidationErrors
+		page, limit := ParsePage(Values(v), &errs)
+		var fields []string
+		for _, e := range errs {
+			fields = append(fields, e.Field)
+		}
+		if got := strings.Join(fields, " "); got != tt.badFields {
+			t.Errorf("ParsePage(%q) invalid fields = %q, want %q", tt.query, got, tt.badFields)
+			continue
+		}
+		if tt.badFields == "" && (page != tt.page || limit != tt.limit) {
+			t.Errorf("ParsePage(%q) = %d, %d; want %d, %d", tt.query, page, limit, tt.page, tt.limit)
+		}
+	}
+}
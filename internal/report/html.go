@@ -0,0 +1,20 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+)
+
+//go:embed laporan.html
+var laporanHTML string
+
+var htmlTemplate = template.Must(template.New("laporan").Funcs(template.FuncMap{
+	"formatDate": FormatDate,
+	"inc":        func(i int) int { return i + 1 },
+}).Parse(laporanHTML))
+
+// WriteHTML renders r as a printable HTML page.
+func (r *CashReport) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
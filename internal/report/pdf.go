@@ -0,0 +1,235 @@
+package report
+
+import (
+	"audit-sendiri/internal/domain"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfMargin   = 20.0
+	pdfWidth    = 210.0 - 2*pdfMargin
+	pdfRowH     = 6.0
+	pdfFont     = "Helvetica"
+	pdfEllipsis = "..."
+)
+
+// transaction table columns, in mm; they add up to pdfWidth.
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"No", 8, "C"},
+	{"Tanggal", 20, "L"},
+	{"Keterangan", 48, "L"},
+	{"Kategori", 28, "L"},
+	{"Masuk", 22, "R"},
+	{"Keluar", 22, "R"},
+	{"Saldo", 22, "R"},
+}
+
+// WritePDF renders r as an A4 report with the RT letterhead, category
+// totals, the transaction table and signature blocks for the Ketua RT and
+// Bendahara. Every page carries the verification hash in its footer.
+func (r *CashReport) WritePDF(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin+5)
+	pdf.SetTitle("Laporan Kas "+r.Period.Label, true)
+	pdf.SetCreator("AuditSendiri", true)
+	pdf.AliasNbPages("")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin)
+		pdf.SetFont(pdfFont, "", 7)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(pdfWidth*0.8, 4, tr("Hash verifikasi: "+r.VerificationHash), "", 0, "L", false, 0, "")
+		pdf.CellFormat(pdfWidth*0.2, 4, fmt.Sprintf("Halaman %d/{nb}", pdf.PageNo()), "", 1, "R", false, 0, "")
+		if r.AuditChainHead != "" {
+			pdf.CellFormat(pdfWidth, 4, tr("Kepala rantai audit: "+r.AuditChainHead), "", 1, "L", false, 0, "")
+		}
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+
+	r.pdfLetterhead(pdf, tr)
+	r.pdfTotals(pdf, tr)
+	r.pdfTransactions(pdf, tr)
+	r.pdfSignatures(pdf, tr)
+
+	return pdf.Output(w)
+}
+
+func (r *CashReport) pdfLetterhead(pdf *fpdf.Fpdf, tr func(string) string) {
+	h := r.Header
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.CellFormat(pdfWidth, 7, tr(fmt.Sprintf("RUKUN TETANGGA %s / RUKUN WARGA %s", h.RTName, h.RWName)), "", 1, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	var region []string
+	if h.Kelurahan != "" {
+		region = append(region, "Kelurahan "+h.Kelurahan)
+	}
+	if h.Kecamatan != "" {
+		region = append(region, "Kecamatan "+h.Kecamatan)
+	}
+	if len(region) > 0 {
+		pdf.CellFormat(pdfWidth, 5, tr(strings.Join(region, ", ")), "", 1, "C", false, 0, "")
+	}
+	if h.Address != "" {
+		pdf.CellFormat(pdfWidth, 5, tr(h.Address), "", 1, "C", false, 0, "")
+	}
+	y := pdf.GetY() + 2
+	pdf.SetLineWidth(0.8)
+	pdf.Line(pdfMargin, y, pdfMargin+pdfWidth, y)
+	pdf.SetLineWidth(0.2)
+	pdf.Line(pdfMargin, y+1, pdfMargin+pdfWidth, y+1)
+	pdf.SetY(y + 6)
+
+	pdf.SetFont(pdfFont, "B", 12)
+	pdf.CellFormat(pdfWidth, 6, "LAPORAN KAS", "", 1, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(pdfWidth, 5, tr("Periode "+r.Period.Label), "", 1, "C", false, 0, "")
+	pdf.Ln(5)
+}
+
+func (r *CashReport) pdfTotals(pdf *fpdf.Fpdf, tr func(string) string) {
+	label, amount := pdfWidth-45, 45.0
+	row := func(text string, m domain.Money, style string) {
+		pdf.SetFont(pdfFont, style, 10)
+		pdf.CellFormat(label, pdfRowH, tr(text), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(amount, pdfRowH, m.String(), "1", 1, "R", false, 0, "")
+	}
+	section := func(text string) {
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.SetFillColor(235, 235, 235)
+		pdf.CellFormat(pdfWidth, pdfRowH, tr(text), "1", 1, "L", true, 0, "")
+	}
+	lines := func(ls []Line, empty string) {
+		if len(ls) == 0 {
+			pdf.SetFont(pdfFont, "I", 10)
+			pdf.CellFormat(pdfWidth, pdfRowH, tr("    "+empty), "1", 1, "L", false, 0, "")
+			return
+		}
+		for i, l := range ls {
+			row(fmt.Sprintf("    %d. %s (%d transaksi)", i+1, l.Category, l.Count), l.Amount, "")
+		}
+	}
+
+	row("Saldo Awal per "+FormatDate(r.Period.Start), r.OpeningBalance, "B")
+	section("Pemasukan")
+	lines(r.Income, "Tidak ada pemasukan")
+	row("Jumlah Pemasukan", r.TotalIncome, "B")
+	section("Pengeluaran")
+	lines(r.Expense, "Tidak ada pengeluaran")
+	row("Jumlah Pengeluaran", r.TotalExpense, "B")
+	row("Saldo Akhir per "+FormatDate(r.Period.LastDay()), r.ClosingBalance, "B")
+	pdf.Ln(8)
+}
+
+func (r *CashReport) pdfTransactions(pdf *fpdf.Fpdf, tr func(string) string) {
+	pdf.SetFont(pdfFont, "B", 11)
+	pdf.CellFormat(pdfWidth, 6, "Rincian Transaksi", "", 1, "L", false, 0, "")
+
+	header := func() {
+		pdf.SetFont(pdfFont, "B", 8)
+		pdf.SetFillColor(235, 235, 235)
+		for _, col := range pdfColumns {
+			pdf.CellFormat(col.width, pdfRowH, col.title, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	header()
+
+	_, pageHeight := pdf.GetPageSize()
+	balance := r.OpeningBalance
+	for i, tx := range r.Transactions {
+		if pdf.GetY()+pdfRowH > pageHeight-pdfMargin-5 {
+			pdf.AddPage()
+			header()
+		}
+		in, out := "", ""
+		if tx.Type == "income" {
+			balance += tx.Amount
+			in = plainAmount(tx.Amount)
+		} else {
+			balance -= tx.Amount
+			out = plainAmount(tx.Amount)
+		}
+		cells := []string{
+			fmt.Sprint(i + 1),
+			tx.Date().In(r.Period.Start.Location()).Format("02/01/2006"),
+			tx.Description,
+			tx.Category,
+			in,
+			out,
+			plainAmount(balance),
+		}
+		pdf.SetFont(pdfFont, "", 8)
+		for j, col := range pdfColumns {
+			text := fitText(pdf, tr, cells[j], col.width-2)
+			pdf.CellFormat(col.width, pdfRowH, tr(text), "1", 0, col.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	if len(r.Transactions) == 0 {
+		pdf.SetFont(pdfFont, "I", 8)
+		pdf.CellFormat(pdfWidth, pdfRowH, "Tidak ada transaksi pada periode ini", "1", 1, "C", false, 0, "")
+	}
+	pdf.Ln(10)
+}
+
+func (r *CashReport) pdfSignatures(pdf *fpdf.Fpdf, tr func(string) string) {
+	const blockH = 45.0
+	_, pageHeight := pdf.GetPageSize()
+	if pdf.GetY()+blockH > pageHeight-pdfMargin-5 {
+		pdf.AddPage()
+	}
+
+	place := r.Header.Kelurahan
+	if place == "" {
+		place = "RT " + r.Header.RTName
+	}
+	half := pdfWidth / 2
+
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.SetX(pdfMargin + half)
+	pdf.CellFormat(half, 5, tr(place+", "+FormatDate(r.GeneratedAt)), "", 1, "C", false, 0, "")
+	pdf.CellFormat(half, 5, "Mengetahui,", "", 0, "C", false, 0, "")
+	pdf.Ln(5)
+	pdf.CellFormat(half, 5, tr("Ketua RT "+r.Header.RTName), "", 0, "C", false, 0, "")
+	pdf.CellFormat(half, 5, "Bendahara", "", 1, "C", false, 0, "")
+	pdf.Ln(22)
+
+	name := func(n string) string {
+		if n == "" {
+			return "(....................................)"
+		}
+		return n
+	}
+	pdf.SetFont(pdfFont, "BU", 10)
+	pdf.CellFormat(half, 5, tr(name(r.Header.KetuaRTName)), "", 0, "C", false, 0, "")
+	pdf.CellFormat(half, 5, tr(name(r.Header.BendaharaName)), "", 1, "C", false, 0, "")
+}
+
+// plainAmount is Money.String without the currency prefix, for table cells.
+func plainAmount(m domain.Money) string {
+	return strings.Replace(m.String(), "Rp ", "", 1)
+}
+
+// fitText shortens s with an ellipsis until its translated form fits in
+// width.
+func fitText(pdf *fpdf.Fpdf, tr func(string) string, s string, width float64) string {
+	if pdf.GetStringWidth(tr(s)) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(tr(string(runes)+pdfEllipsis)) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + pdfEllipsis
+}
@@ -0,0 +1,174 @@
+// Package report builds the periodic cash report (laporan kas) residents
+// receive from the treasurer.
+package report
+
+import (
+	"audit-sendiri/internal/domain"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Source is the part of the store a report is built from. It must be a
+// single snapshot, so the totals, transactions and audit head agree.
+type Source interface {
+	Settings() domain.AppSettings
+	Summary(q domain.SummaryQuery) domain.Summary
+	Transactions() []domain.Transaction
+	AuditLogs() []domain.AuditLog
+}
+
+// Period is the [Start, End) range a report covers.
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Label string    `json:"label"`
+}
+
+var monthNames = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+// Month returns the calendar month in loc.
+func Month(year int, month time.Month, loc *time.Location) Period {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return Period{
+		Start: start,
+		End:   start.AddDate(0, 1, 0),
+		Label: fmt.Sprintf("%s %d", monthNames[month-1], year),
+	}
+}
+
+// Year returns the calendar year in loc.
+func Year(year int, loc *time.Location) Period {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	return Period{
+		Start: start,
+		End:   start.AddDate(1, 0, 0),
+		Label: fmt.Sprintf("Tahun %d", year),
+	}
+}
+
+// Range covers start up to but not including end.
+func Range(start, end time.Time) Period {
+	return Period{
+		Start: start,
+		End:   end,
+		Label: FormatDate(start) + " s.d. " + FormatDate(end.Add(-time.Nanosecond)),
+	}
+}
+
+// LastDay is the last instant inside the period, for "per <date>" labels.
+func (p Period) LastDay() time.Time {
+	return p.End.Add(-time.Nanosecond)
+}
+
+// FormatDate formats t as an Indonesian long date, e.g. "5 Oktober 2026".
+func FormatDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), monthNames[t.Month()-1], t.Year())
+}
+
+// Line is the total of one category within the period.
+type Line struct {
+	CategoryID string       `json:"category_id,omitempty"`
+	Category   string       `json:"category"`
+	Amount     domain.Money `json:"amount"`
+	Count      int          `json:"count"`
+}
+
+type CashReport struct {
+	Header           domain.AppSettings `json:"header"`
+	Period           Period             `json:"period"`
+	OpeningBalance   domain.Money       `json:"opening_balance"`
+	Income           []Line             `json:"income"`
+	Expense          []Line             `json:"expense"`
+	TotalIncome      domain.Money       `json:"total_income"`
+	TotalExpense     domain.Money       `json:"total_expense"`
+	Net              domain.Money       `json:"net"`
+	ClosingBalance   domain.Money       `json:"closing_balance"`
+	TransactionCount int                `json:"transaction_count"`
+	// Transactions are the live transactions in the period, oldest first.
+	Transactions []domain.Transaction `json:"transactions"`
+	// VerificationHash is the SHA-256 of the period, balances, transactions
+	// and audit chain head. Regenerating the report yields the same hash for
+	// as long as neither its transactions nor the audit log change.
+	VerificationHash string `json:"verification_hash"`
+	// AuditChainHead is the hash of the newest audit log entry when the
+	// report was generated.
+	AuditChainHead string    `json:"audit_chain_head,omitempty"`
+	GeneratedAt    time.Time `json:"generated_at"`
+}
+
+// Generate builds the cash report for p. The opening balance covers every
+// transaction before p.Start, so consecutive reports chain: one period's
+// closing balance is the next period's opening balance.
+func Generate(src Source, p Period, now time.Time) (*CashReport, error) {
+	start, end := p.Start, p.End
+	s := src.Summary(domain.SummaryQuery{
+		From:     &start,
+		To:       &end,
+		Location: p.Start.Location(),
+		Interval: domain.IntervalMonth,
+	})
+
+	r := &CashReport{
+		Header:           src.Settings(),
+		Period:           p,
+		OpeningBalance:   s.OpeningBalance,
+		Income:           []Line{},
+		Expense:          []Line{},
+		TotalIncome:      s.TotalIncome,
+		TotalExpense:     s.TotalExpense,
+		Net:              s.Balance,
+		ClosingBalance:   s.ClosingBalance,
+		TransactionCount: s.TransactionCount,
+		Transactions:     []domain.Transaction{},
+		GeneratedAt:      now,
+	}
+	for _, c := range s.Categories {
+		line := Line{CategoryID: c.CategoryID, Category: c.Category, Amount: c.Total, Count: c.Count}
+		if c.Type == "income" {
+			r.Income = append(r.Income, line)
+		} else {
+			r.Expense = append(r.Expense, line)
+		}
+	}
+
+	for _, tx := range src.Transactions() {
+		if tx.DeletedAt == nil && !tx.Date().Before(start) && tx.Date().Before(end) {
+			r.Transactions = append(r.Transactions, tx)
+		}
+	}
+	sort.SliceStable(r.Transactions, func(i, j int) bool {
+		return r.Transactions[i].Date().Before(r.Transactions[j].Date())
+	})
+	if logs := src.AuditLogs(); len(logs) > 0 {
+		r.AuditChainHead = logs[len(logs)-1].Hash
+	}
+	hash, err := r.digest()
+	if err != nil {
+		return nil, err
+	}
+	r.VerificationHash = hash
+	return r, nil
+}
+
+func (r *CashReport) digest() (string, error) {
+	payload, err := json.Marshal(struct {
+		Start          time.Time            `json:"start"`
+		End            time.Time            `json:"end"`
+		OpeningBalance domain.Money         `json:"opening_balance"`
+		ClosingBalance domain.Money         `json:"closing_balance"`
+		Transactions   []domain.Transaction `json:"transactions"`
+		AuditChainHead string               `json:"audit_chain_head"`
+	}{r.Period.Start.UTC(), r.Period.End.UTC(), r.OpeningBalance, r.ClosingBalance, r.Transactions, r.AuditChainHead})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"audit-sendiri/internal/db"
+	"audit-sendiri/internal/domain"
+	"testing"
+	"time"
+)
+
+func generate(t *testing.T, store domain.Store, p Period) *CashReport {
+	t.Helper()
+	var r *CashReport
+	err := store.View(func(s domain.Snapshot) error {
+		var err error
+		r, err = Generate(s, p, p.End)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestGenerateHashIsReproducible(t *testing.T) {
+	dir := t.TempDir()
+	store, err := db.Open(dir, db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(m time.Month, d int) *time.Time {
+		date := time.Date(2026, m, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	for _, tx := range []domain.Transaction{
+		{ID: "t1", Type: "income", Amount: 500000, Category: "Iuran", TransactionDate: day(time.March, 1)},
+		{ID: "t2", Type: "expense", Amount: 125000, Category: "Kebersihan", TransactionDate: day(time.March, 2)},
+	} {
+		tx.CreatedAt = *tx.TransactionDate
+		if err := store.InsertTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.InsertAuditLog(domain.AuditLog{ID: "a1", Action: "create"}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Month(2026, time.March, time.UTC)
+	r := generate(t, store, p)
+	if r.TotalIncome != 500000 || r.TotalExpense != 125000 || len(r.Transactions) != 2 {
+		t.Fatalf("report = %s in, %s out, %d transactions", r.TotalIncome, r.TotalExpense, len(r.Transactions))
+	}
+	if r.AuditChainHead == "" {
+		t.Fatal("report has no audit chain head")
+	}
+
+	// A commit outside the period that writes no audit entry, and reopening
+	// the store, leave the printed hash reproducible.
+	later := domain.Transaction{ID: "t3", Type: "income", Amount: 1000, Category: "Iuran", TransactionDate: day(time.April, 1)}
+	if err := store.InsertTransaction(later); err != nil {
+		t.Fatal(err)
+	}
+	if again := generate(t, store, p); again.VerificationHash != r.VerificationHash {
+		t.Error("an unrelated commit changed the hash")
+	}
+	store.Close()
+	store, err = db.Open(dir, db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if again := generate(t, store, p); again.VerificationHash != r.VerificationHash {
+		t.Error("reopening the store changed the hash")
+	}
+
+	// The hash covers the audit chain head.
+	if err := store.InsertAuditLog(domain.AuditLog{ID: "a2", Action: "update"}); err != nil {
+		t.Fatal(err)
+	}
+	if again := generate(t, store, p); again.VerificationHash == r.VerificationHash {
+		t.Error("a new audit entry did not change the hash")
+	}
+}